@@ -0,0 +1,2 @@
+DROP TRIGGER tasks_notify ON tasks;
+DROP FUNCTION notify_tasks();
@@ -0,0 +1,16 @@
+-- Every change to tasks is notified on the "tasks" channel, streamed at /api/events/tasks
+-- when db.notify.channels lists it. The payload only carries the operation and the id:
+-- NOTIFY payloads are limited to 8000 bytes.
+CREATE FUNCTION notify_tasks() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('tasks', json_build_object(
+        'op', lower(TG_OP),
+        'id', CASE WHEN TG_OP = 'DELETE' THEN OLD.id ELSE NEW.id END
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tasks_notify
+    AFTER INSERT OR UPDATE OR DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION notify_tasks();
@@ -1,46 +1,254 @@
 package bootstrap
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"log/slog"
+	"net/http"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/khedhrije/tools-archetype/internal/configuration"
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres"
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres/notify"
 	"github.com/khedhrije/tools-archetype/internal/ui/rest/router"
+	"github.com/khedhrije/tools-archetype/pkg/featureflag"
 	"github.com/khedhrije/tools-archetype/pkg/monitoring"
 )
 
+// Process exit codes returned by main.
+const (
+	ExitOK             = 0 // clean shutdown
+	ExitStartupFailure = 1 // configuration, listener or dependency could not be set up
+	ExitRuntimeFailure = 2 // the server failed while serving, or draining did not complete
+)
+
+// ErrStartup wraps every error that prevents the service from starting.
+var ErrStartup = errors.New("startup failure")
+
+// ExitCode maps the error returned by InitBootstrap or Run to a process exit code.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrStartup):
+		return ExitStartupFailure
+	default:
+		return ExitRuntimeFailure
+	}
+}
+
 type Bootstrap struct {
-	Config *configuration.AppConfig
-	Router *gin.Engine
+	Config        *configuration.AppConfig // configuration at startup; use configuration.Get for live values
+	Router        *gin.Engine              // public routes, or every route with rest.single_port
+	AdminRouter   *gin.Engine              // technical routes and monitoring UI; nil with rest.single_port
+	ConfigWatcher *configuration.Watcher
+	FeatureFlags  *featureflag.Set
+	// Lifecycle starts background components before serving and stops them after draining.
+	Lifecycle *Lifecycle
+	// Readiness holds the gates behind /api/readyz.
+	Readiness *monitoring.Readiness
+	// Watchdog receives the heartbeats of long-running loops, checked by /api/livez.
+	Watchdog *monitoring.Watchdog
+	// Warmup holds the one-shot tasks run after the components start, reported by /api/startupz.
+	Warmup *Warmup
+	// DB is the shared PostgreSQL pool, nil when the database is not configured.
+	DB *pgxpool.Pool
+	// DBTracer records the queries run on DB, nil without DB or with db.trace.enabled off.
+	DBTracer *postgres.Tracer
+	// Notifications streams the db.notify.channels at /api/events, nil without DB or channels.
+	Notifications *notify.Listener
+
+	draining atomic.Bool
+	certs    *certStore // public listener certificates; nil without TLS
 }
 
-func InitBootstrap() Bootstrap {
+func InitBootstrap() (*Bootstrap, error) {
 	return initBootstrap()
 }
 
 // initBootstrap sets up the application configuration, initializes services, and configures the router.
-func initBootstrap() Bootstrap {
-	if configuration.Config == nil {
-		log.Fatal("configuration is nil")
+func initBootstrap() (*Bootstrap, error) {
+	snap := configuration.Current()
+	if snap == nil {
+		return nil, fmt.Errorf("%w: configuration is not loaded", ErrStartup)
+	}
+	if err := snap.Config.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStartup, err)
+	}
+
+	app := &Bootstrap{Lifecycle: NewLifecycle(), Warmup: NewWarmup()}
+	app.Config = snap.Config
+
+	initLogging(app.Config)
+	slog.Info("configuration loaded", "env", app.Config.Env, "file", snap.File)
+	for _, key := range snap.Keys() {
+		slog.Debug("configuration value", "key", key, "source", snap.Sources[key])
+	}
+	for _, d := range snap.Deprecations {
+		slog.Warn("deprecated environment variable", "key", d.Key, "used", d.Legacy, "useInstead", d.Canonical)
+	}
+
+	// Hot reload: subscribers see every validated configuration swap
+	configuration.Subscribe(onConfigReload)
+	if err := app.Lifecycle.Register(Component{
+		Name: "config-watcher",
+		Start: func(context.Context) error {
+			watcher, err := configuration.Watch()
+			if err != nil {
+				slog.Warn("configuration hot reload disabled", "error", err)
+			}
+			app.ConfigWatcher = watcher
+			return nil
+		},
+		Stop: func(context.Context) error { return app.ConfigWatcher.Stop() },
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStartup, err)
 	}
 
-	app := Bootstrap{}
-	app.Config = configuration.Config
+	app.FeatureFlags = initFeatureFlags(app.Config)
+	app.initReadiness()
+	app.Watchdog = monitoring.NewWatchdog(watchdogLimits)
+	if err := app.initDatabase(); err != nil {
+		return nil, fmt.Errorf("%w: database: %w", ErrStartup, err)
+	}
+	if err := app.initNotifications(); err != nil {
+		return nil, fmt.Errorf("%w: notifications: %w", ErrStartup, err)
+	}
 
-	monitoringHandler := monitoring.New()
+	if tlsCfg := app.Config.RestConfig.TLS; tlsCfg.Enabled() {
+		certs, err := newCertStore(tlsCfg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: TLS: %w", ErrStartup, err)
+		}
+		app.certs = certs
+		if err := app.Lifecycle.Register(certs.component(app.Watchdog)); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrStartup, err)
+		}
+	}
 
-	// ✅ Create router
-	r := router.CreateRouter(monitoringHandler)
-	app.Router = r
+	monitoringOpts := []monitoring.Option{
+		monitoring.WithReadiness(app.Readiness),
+		monitoring.WithComponents(app.Lifecycle.Status),
+		monitoring.WithStartup(app.Warmup.Status),
+		monitoring.WithWatchdog(app.Watchdog),
+	}
+	if app.DB != nil {
+		monitoringOpts = append(monitoringOpts, monitoring.WithDatabase(app.DB))
+	}
+	if app.DBTracer != nil {
+		monitoringOpts = append(monitoringOpts, monitoring.WithQueryTracer(app.DBTracer))
+	}
+	if app.Notifications != nil {
+		monitoringOpts = append(monitoringOpts, monitoring.WithNotifications(app.Notifications))
+	}
+	if app.certs != nil {
+		monitoringOpts = append(monitoringOpts, monitoring.WithCertificates(app.certs.certificates))
+	}
+	monitoringHandler := monitoring.New(monitoringOpts...)
 
-	return app
+	// ✅ Create routers: technical routes stay off the public port unless single_port is set
+	if app.Config.RestConfig.SinglePort {
+		app.Router = router.CreateRouter(monitoringHandler, app.DB, app.Notifications, app.FeatureFlags)
+	} else {
+		app.Router = router.CreatePublicRouter(app.DB, app.Notifications, app.FeatureFlags)
+		app.AdminRouter = router.CreateAdminRouter(monitoringHandler, app.Notifications, app.FeatureFlags)
+	}
+
+	return app, nil
+}
+
+// Run starts the lifecycle components, serves HTTP while the warm-up tasks run,
+// until SIGINT or SIGTERM, then shuts down gracefully:
+//
+//  1. readiness starts failing so the load balancer stops routing new requests
+//  2. the servers keep serving for rest.pre_stop_delay while endpoints are updated
+//  3. in-flight requests are drained within rest.shutdown_timeout
+//  4. lifecycle components are stopped in reverse start order
+//
+// It returns nil on a clean shutdown; see ExitCode for the other outcomes.
+func (b *Bootstrap) Run() error {
+	rc := b.Config.RestConfig
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := b.Lifecycle.Start(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrStartup, err)
+	}
+
+	servers, err := b.listen()
+	if err != nil {
+		return errors.Join(fmt.Errorf("%w: %w", ErrStartup, err), b.Lifecycle.Stop(context.Background()))
+	}
+
+	pending := 0
+	for _, s := range servers {
+		pending += len(s.lns)
+	}
+	serveErr := make(chan error, pending)
+	for _, s := range servers {
+		for _, ln := range s.lns {
+			go func() { serveErr <- s.serve(ln) }()
+			slog.Info("server listening", "server", s.name, "network", ln.Addr().Network(), "addr", ln.Addr().String())
+		}
+	}
+
+	// Warm-up runs while serving so /api/startupz can report its progress.
+	warmupDone := make(chan struct{})
+	go func() {
+		defer close(warmupDone)
+		if err := b.Warmup.Run(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("warm-up did not complete, the service will not become ready", "error", err)
+		}
+	}()
+
+	var errs []error
+	select {
+	case err := <-serveErr:
+		// A server stopped on its own: shut the others down without a pre-stop delay.
+		errs = append(errs, err)
+		pending--
+		cancel()
+	case <-ctx.Done():
+		cancel() // a second signal now terminates the process immediately
+
+		b.draining.Store(true)
+		slog.Info("shutdown requested, readiness now failing", "preStopDelay", rc.PreStopDelay)
+		time.Sleep(rc.PreStopDelay)
+	}
+
+	slog.Info("draining connections", "timeout", rc.ShutdownTimeout)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), rc.ShutdownTimeout)
+	defer cancelShutdown()
+
+	errs = append(errs, shutdown(shutdownCtx, servers))
+	for ; pending > 0; pending-- {
+		if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, err)
+		}
+	}
+	<-warmupDone // canceled with ctx; tasks must not outlive the components they use
+	// Components get their own timeouts: draining may have used up the shutdown deadline.
+	errs = append(errs, b.Lifecycle.Stop(context.Background()))
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	slog.Info("shutdown complete")
+	return nil
 }
 
-func (b Bootstrap) Run() {
-	dsn := fmt.Sprintf("%s:%d", b.Config.RestConfig.Host, b.Config.RestConfig.Port)
-	if errRun := b.Router.Run(dsn); errRun != nil {
-		slog.Error("error during service instantiation")
+// watchdogLimits reads the liveness ceilings from the live configuration.
+func watchdogLimits() monitoring.WatchdogLimits {
+	lc := configuration.Get().LivenessConfig
+	return monitoring.WatchdogLimits{
+		MaxGoroutines:  lc.MaxGoroutines,
+		MaxMemoryBytes: uint64(lc.MaxMemoryMB) << 20,
 	}
 }
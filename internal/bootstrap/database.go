@@ -0,0 +1,103 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres"
+	"github.com/khedhrije/tools-archetype/migrations"
+	"github.com/khedhrije/tools-archetype/pkg/monitoring"
+)
+
+// databasePingTimeout bounds the startup ping and the readiness probe.
+const databasePingTimeout = 2 * time.Second
+
+// initDatabase builds the shared pool, traced unless db.trace.enabled is off, registered as the
+// "database" component and readiness gate, and sets up the migrations. Without database settings,
+// b.DB stays nil and nothing is registered.
+func (b *Bootstrap) initDatabase() error {
+	pc, err := postgres.PoolConfig(b.Config.DatabaseConfig)
+	if errors.Is(err, postgres.ErrNotConfigured) {
+		slog.Info("database not configured, no connection pool")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if tc := b.Config.DatabaseConfig.Trace; tc.Enabled {
+		tracer := postgres.NewTracer(tc.SlowThreshold, tc.SlowQueries)
+		configuration.Subscribe(func(_, new *configuration.AppConfig) {
+			tracer.SetSlowThreshold(new.DatabaseConfig.Trace.SlowThreshold)
+		})
+		pc.ConnConfig.Tracer = tracer
+		b.DBTracer = tracer
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), pc)
+	if err != nil {
+		return err
+	}
+	b.DB = pool
+	if err := b.initMigrations(pool); err != nil {
+		pool.Close()
+		return err
+	}
+
+	b.Readiness.Add(monitoring.Gate{
+		Name:     "database",
+		Check:    func(ctx context.Context) (monitoring.Detail, error) { return nil, pool.Ping(ctx) },
+		Timeout:  databasePingTimeout,
+		CacheFor: readinessCacheTTL,
+	})
+	return b.Lifecycle.Register(Component{
+		Name: "database",
+		// An unreachable database does not fail startup: the readiness gate keeps
+		// the instance out of rotation until it answers.
+		Start: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, databasePingTimeout)
+			defer cancel()
+			if err := pool.Ping(ctx); err != nil {
+				slog.Warn("database unreachable at startup", "error", err)
+			}
+			return nil
+		},
+		// Close waits for acquired connections to be released: the servers are drained by now.
+		Stop: func(context.Context) error {
+			pool.Close()
+			return nil
+		},
+	})
+}
+
+// initMigrations gates readiness on the schema being up to date with the embedded
+// migrations and, with db.migrations.auto, applies them as a warm-up task. Replicas
+// starting together wait for each other on the migration lock.
+func (b *Bootstrap) initMigrations(pool *pgxpool.Pool) error {
+	migrator, err := migrations.New(pool)
+	if err != nil {
+		return err
+	}
+	b.Readiness.Add(monitoring.Gate{
+		Name: "migrations",
+		Check: func(ctx context.Context) (monitoring.Detail, error) {
+			st, err := migrator.Status(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return monitoring.Detail{"current": st.Current, "latest": st.Latest}, st.Err()
+		},
+		Timeout:  databasePingTimeout,
+		CacheFor: readinessCacheTTL,
+	})
+
+	mc := b.Config.DatabaseConfig.Migrations
+	if !mc.Auto {
+		slog.Info("automatic migrations disabled, readiness waits for the migrate command")
+		return nil
+	}
+	b.Warmup.Add(Task{Name: "migrations", Run: migrator.Up, Timeout: mc.Timeout})
+	return nil
+}
@@ -0,0 +1,43 @@
+package bootstrap
+
+import (
+	"log/slog"
+	"path/filepath"
+
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+	"github.com/khedhrije/tools-archetype/pkg/featureflag"
+)
+
+// featureFlagsFile stores runtime overrides inside the data dir so they survive restarts.
+const featureFlagsFile = "feature-flags.json"
+
+// initFeatureFlags builds the flag set from configuration and restores persisted overrides.
+func initFeatureFlags(cfg *configuration.AppConfig) *featureflag.Set {
+	flags := featureflag.New(featureDefinitions(cfg),
+		featureflag.WithStore(filepath.Join(cfg.AppDataDir, featureFlagsFile)),
+		featureflag.WithTenantHeader(cfg.FeaturesConfig.TenantHeader),
+	)
+	if err := flags.LoadOverrides(); err != nil {
+		slog.Error("unable to restore feature flag overrides", "error", err)
+	}
+
+	configuration.Subscribe(func(_, new *configuration.AppConfig) {
+		flags.SetDefinitions(featureDefinitions(new))
+	})
+	return flags
+}
+
+func featureDefinitions(cfg *configuration.AppConfig) []featureflag.Definition {
+	defs := make([]featureflag.Definition, 0, len(cfg.FeaturesConfig.Flags))
+	for name, f := range cfg.FeaturesConfig.Flags {
+		defs = append(defs, featureflag.Definition{
+			Name:        name,
+			Description: f.Description,
+			Enabled:     f.Enabled,
+			Percentage:  f.Percentage,
+			Tenants:     f.Tenants,
+			Headers:     f.Headers,
+		})
+	}
+	return defs
+}
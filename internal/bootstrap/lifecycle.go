@@ -0,0 +1,201 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/khedhrije/tools-archetype/pkg/monitoring"
+)
+
+// defaultComponentTimeout bounds each Start and Stop hook when the component does not set its own.
+const defaultComponentTimeout = 30 * time.Second
+
+// State is the lifecycle state of a component.
+type State string
+
+const (
+	StatePending  State = "pending"
+	StateStarting State = "starting"
+	StateRunning  State = "running"
+	StateFailed   State = "failed"
+	StateStopping State = "stopping"
+	StateStopped  State = "stopped"
+)
+
+// Component is something with a start/stop lifecycle: a DB pool, a scheduler, a cache...
+// Both hooks are optional.
+type Component struct {
+	Name      string
+	DependsOn []string // started before, stopped after this component
+	Start     func(ctx context.Context) error
+	Stop      func(ctx context.Context) error
+	Timeout   time.Duration // per hook, defaultComponentTimeout when zero
+}
+
+type component struct {
+	Component
+	state State
+	err   error
+	since time.Time
+}
+
+// Lifecycle starts components in dependency order and stops them in reverse.
+type Lifecycle struct {
+	mu         sync.Mutex
+	components []*component
+	byName     map[string]*component
+	started    []*component // in start order, what Stop walks back
+}
+
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{byName: map[string]*component{}}
+}
+
+// Register adds a component. Names must be unique; dependencies may be registered later.
+func (l *Lifecycle) Register(c Component) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c.Name == "" {
+		return errors.New("lifecycle: component name is required")
+	}
+	if _, dup := l.byName[c.Name]; dup {
+		return fmt.Errorf("lifecycle: component %q already registered", c.Name)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultComponentTimeout
+	}
+	entry := &component{Component: c, state: StatePending, since: time.Now()}
+	l.components = append(l.components, entry)
+	l.byName[c.Name] = entry
+	return nil
+}
+
+// Start starts every component after its dependencies. When one fails, the
+// components already running are stopped in reverse order and the error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	order, err := l.order()
+	if err != nil {
+		return err
+	}
+	for _, c := range order {
+		l.set(c, StateStarting, nil)
+		start := time.Now()
+		if err := call(ctx, c.Start, c.Timeout); err != nil {
+			err = fmt.Errorf("start %s: %w", c.Name, err)
+			l.set(c, StateFailed, err)
+			return errors.Join(err, l.Stop(context.WithoutCancel(ctx)))
+		}
+		l.mu.Lock()
+		l.started = append(l.started, c)
+		l.mu.Unlock()
+		l.set(c, StateRunning, nil)
+		slog.Debug("component started", "component", c.Name, "took", time.Since(start))
+	}
+	return nil
+}
+
+// Stop stops the started components in reverse start order, each within its own
+// timeout and ctx. Every component is given a chance to stop; errors are joined.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	started := l.started
+	l.started = nil
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		l.set(c, StateStopping, nil)
+		if err := call(ctx, c.Stop, c.Timeout); err != nil {
+			err = fmt.Errorf("stop %s: %w", c.Name, err)
+			l.set(c, StateFailed, err)
+			errs = append(errs, err)
+			continue
+		}
+		l.set(c, StateStopped, nil)
+		slog.Debug("component stopped", "component", c.Name)
+	}
+	return errors.Join(errs...)
+}
+
+// Status reports every component in registration order.
+func (l *Lifecycle) Status() []monitoring.Component {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]monitoring.Component, 0, len(l.components))
+	for _, c := range l.components {
+		st := monitoring.Component{
+			Name:      c.Name,
+			State:     string(c.state),
+			DependsOn: c.DependsOn,
+			Since:     c.since,
+		}
+		if c.err != nil {
+			st.Error = c.err.Error()
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+func (l *Lifecycle) set(c *component, state State, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c.state, c.err, c.since = state, err, time.Now()
+}
+
+// order sorts the components topologically, keeping registration order between independent ones.
+func (l *Lifecycle) order() ([]*component, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	marks := map[string]int{}
+	order := make([]*component, 0, len(l.components))
+
+	var visit func(c *component, path []string) error
+	visit = func(c *component, path []string) error {
+		switch marks[c.Name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("lifecycle: dependency cycle %v", append(path, c.Name))
+		}
+		marks[c.Name] = visiting
+		for _, dep := range c.DependsOn {
+			d, ok := l.byName[dep]
+			if !ok {
+				return fmt.Errorf("lifecycle: %s depends on unknown component %q", c.Name, dep)
+			}
+			if err := visit(d, append(path, c.Name)); err != nil {
+				return err
+			}
+		}
+		marks[c.Name] = done
+		order = append(order, c)
+		return nil
+	}
+	for _, c := range l.components {
+		if err := visit(c, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// call runs hook (when set) with its own timeout derived from ctx.
+func call(ctx context.Context, hook func(context.Context) error, timeout time.Duration) error {
+	if hook == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return hook(ctx)
+}
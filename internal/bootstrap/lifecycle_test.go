@@ -0,0 +1,138 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testComponent is a component that records its start and stop in events.
+type testComponent struct {
+	name      string
+	dependsOn []string
+	failStart bool
+}
+
+func TestLifecycleOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []testComponent
+		wantEvents []string // start and stop events, in order
+		wantErr    string   // substring of the Start error, empty when it succeeds
+	}{
+		{
+			name:       "registration order without dependencies",
+			components: []testComponent{{name: "a"}, {name: "b"}, {name: "c"}},
+			wantEvents: []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"},
+		},
+		{
+			name: "dependencies first, registered later",
+			components: []testComponent{
+				{name: "api", dependsOn: []string{"cache", "db"}},
+				{name: "cache", dependsOn: []string{"db"}},
+				{name: "db"},
+			},
+			wantEvents: []string{"start db", "start cache", "start api", "stop api", "stop cache", "stop db"},
+		},
+		{
+			name: "diamond starts the shared dependency once",
+			components: []testComponent{
+				{name: "top", dependsOn: []string{"left", "right"}},
+				{name: "left", dependsOn: []string{"base"}},
+				{name: "right", dependsOn: []string{"base"}},
+				{name: "base"},
+			},
+			wantEvents: []string{"start base", "start left", "start right", "start top", "stop top", "stop right", "stop left", "stop base"},
+		},
+		{
+			name: "cycle",
+			components: []testComponent{
+				{name: "a", dependsOn: []string{"b"}},
+				{name: "b", dependsOn: []string{"c"}},
+				{name: "c", dependsOn: []string{"a"}},
+			},
+			wantErr: "dependency cycle [a b c a]",
+		},
+		{
+			name:       "self dependency",
+			components: []testComponent{{name: "a", dependsOn: []string{"a"}}},
+			wantErr:    "dependency cycle [a a]",
+		},
+		{
+			name:       "unknown dependency",
+			components: []testComponent{{name: "a", dependsOn: []string{"missing"}}},
+			wantErr:    `a depends on unknown component "missing"`,
+		},
+		{
+			name: "failed start stops the started ones in reverse order",
+			components: []testComponent{
+				{name: "db"},
+				{name: "cache", dependsOn: []string{"db"}},
+				{name: "api", dependsOn: []string{"cache"}, failStart: true},
+			},
+			wantEvents: []string{"start db", "start cache", "start api", "stop cache", "stop db"},
+			wantErr:    "start api: boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			l := NewLifecycle()
+			for _, tc := range tt.components {
+				err := l.Register(Component{
+					Name:      tc.name,
+					DependsOn: tc.dependsOn,
+					Start: func(context.Context) error {
+						events = append(events, "start "+tc.name)
+						if tc.failStart {
+							return errors.New("boom")
+						}
+						return nil
+					},
+					Stop: func(context.Context) error {
+						events = append(events, "stop "+tc.name)
+						return nil
+					},
+				})
+				if err != nil {
+					t.Fatalf("Register(%s): %v", tc.name, err)
+				}
+			}
+
+			err := l.Start(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Start() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Start() error = %v", err)
+				}
+				if err := l.Stop(context.Background()); err != nil {
+					t.Fatalf("Stop() error = %v", err)
+				}
+			}
+			if !slices.Equal(events, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", events, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestLifecycleRegister(t *testing.T) {
+	l := NewLifecycle()
+	if err := l.Register(Component{}); err == nil {
+		t.Error("Register() without a name succeeded")
+	}
+	if err := l.Register(Component{Name: "a"}); err != nil {
+		t.Fatalf("Register(a): %v", err)
+	}
+	if err := l.Register(Component{Name: "a"}); err == nil {
+		t.Error("Register() of a duplicate name succeeded")
+	}
+	if got := l.Status(); len(got) != 1 || got[0].State != string(StatePending) {
+		t.Errorf("Status() = %+v, want one pending component", got)
+	}
+}
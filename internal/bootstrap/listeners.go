@@ -0,0 +1,141 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+)
+
+// Socket activation protocol, see sd_listen_fds(3): the passed descriptors start at 3.
+const (
+	listenFdsStart = 3
+	// adminSocketName routes a passed socket named with FileDescriptorName=admin to the admin server.
+	adminSocketName = "admin"
+)
+
+// systemdListeners returns the sockets systemd passed to this process (LISTEN_FDS), keyed by
+// FileDescriptorName ("unknown" when unnamed). The variables are unset so children do not inherit them.
+func systemdListeners() (map[string][]net.Listener, error) {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil // not meant for us
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	out := map[string][]net.Listener{}
+	for i := range n {
+		fd := listenFdsStart + i
+		syscall.CloseOnExec(fd)
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		ln, err := net.FileListener(f) // dups the descriptor
+		_ = f.Close()
+		if err != nil {
+			closeAll(out)
+			return nil, fmt.Errorf("systemd socket %d (%s): %w", fd, name, err)
+		}
+		out[name] = append(out[name], ln)
+	}
+	return out, nil
+}
+
+// listenUnix listens on the configured socket path, replacing a stale socket left by a
+// previous run, then applies the configured mode and owner.
+func listenUnix(rc *configuration.RestConfig) (net.Listener, error) {
+	path := rc.UnixSocket
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// Closing the listener removes the socket file.
+	ln.(*net.UnixListener).SetUnlinkOnClose(true)
+
+	mode, _ := strconv.ParseUint(rc.UnixSocketMode, 8, 32) // validated with the configuration
+	if err := os.Chmod(path, fs.FileMode(mode)); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	if rc.UnixSocketOwner != "" {
+		uid, gid, err := lookupOwner(rc.UnixSocketOwner)
+		if err == nil {
+			err = os.Chown(path, uid, gid)
+		}
+		if err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("set owner %q: %w", rc.UnixSocketOwner, err)
+		}
+	}
+	return ln, nil
+}
+
+// removeStaleSocket deletes path when it is a socket nobody accepts on. A live socket
+// means another instance is running; any other kind of file is left alone.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+	return os.Remove(path)
+}
+
+// lookupOwner resolves "user:group", names or numeric ids.
+func lookupOwner(owner string) (uid, gid int, err error) {
+	u, g, _ := strings.Cut(owner, ":")
+	if uid, err = strconv.Atoi(u); err != nil {
+		usr, lookupErr := user.Lookup(u)
+		if lookupErr != nil {
+			return 0, 0, lookupErr
+		}
+		uid, _ = strconv.Atoi(usr.Uid)
+	}
+	if gid, err = strconv.Atoi(g); err != nil {
+		grp, lookupErr := user.LookupGroup(g)
+		if lookupErr != nil {
+			return 0, 0, lookupErr
+		}
+		gid, _ = strconv.Atoi(grp.Gid)
+	}
+	return uid, gid, nil
+}
+
+func closeAll(lns map[string][]net.Listener) {
+	for _, group := range lns {
+		for _, ln := range group {
+			_ = ln.Close()
+		}
+	}
+}
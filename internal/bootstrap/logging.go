@@ -0,0 +1,42 @@
+package bootstrap
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+)
+
+// logLevel backs the default slog logger so the level can follow configuration reloads.
+var logLevel = new(slog.LevelVar)
+
+// initLogging installs the default logger at the configured level.
+func initLogging(cfg *configuration.AppConfig) {
+	logLevel.Set(parseLevel(cfg.LogConfig.Level))
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+}
+
+// onConfigReload applies the settings that can change at runtime and warns about the others.
+func onConfigReload(old, new *configuration.AppConfig) {
+	if old.LogConfig.Level != new.LogConfig.Level {
+		logLevel.Set(parseLevel(new.LogConfig.Level))
+		slog.Info("log level changed", "from", old.LogConfig.Level, "to", new.LogConfig.Level)
+	}
+	if *old.RestConfig != *new.RestConfig {
+		slog.Warn("REST listener settings changed; restart required to apply them")
+	}
+}
+
+func parseLevel(s string) slog.Level {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,74 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres"
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres/notify"
+	"github.com/khedhrije/tools-archetype/pkg/monitoring"
+)
+
+var errNotificationsDisconnected = errors.New("notification listener disconnected")
+
+// initNotifications sets up the LISTEN/NOTIFY listener on its own connection, registered as
+// the "notifications" component and readiness gate. Without a database or db.notify.channels,
+// b.Notifications stays nil and /api/events is not routed.
+func (b *Bootstrap) initNotifications() error {
+	nc := b.Config.DatabaseConfig.Notify
+	if b.DB == nil || len(nc.Channels) == 0 {
+		return nil
+	}
+	// Same settings as the pool (TLS included), untraced: waiting for a notification is no query.
+	pc, err := postgres.PoolConfig(b.Config.DatabaseConfig)
+	if err != nil {
+		return err
+	}
+	l := notify.New(pc.ConnConfig, nc.Channels, notify.Options{
+		Buffer:            nc.ClientBuffer,
+		MaxReconnectDelay: nc.MaxReconnectDelay,
+	})
+	b.Notifications = l
+
+	b.Readiness.Add(monitoring.Gate{
+		Name: "notifications",
+		Check: func(context.Context) (monitoring.Detail, error) {
+			st := l.Status()
+			detail := monitoring.Detail{"channels": st.Channels, "reconnects": st.Reconnects}
+			if !st.Connected {
+				return detail, errNotificationsDisconnected
+			}
+			return detail, nil
+		},
+	})
+
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+	return b.Lifecycle.Register(Component{
+		Name:      "notifications",
+		DependsOn: []string{"database"},
+		// The connection is opened in the background: like the pool, an unreachable database
+		// only keeps the instance unready.
+		Start: func(context.Context) error {
+			hb := b.Watchdog.Register("notifications", l.HeartbeatInterval())
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go func() {
+				defer close(done)
+				defer hb.Stop()
+				l.Run(ctx, hb.Beat)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			l.Close()
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
@@ -0,0 +1,62 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+	"github.com/khedhrije/tools-archetype/pkg/monitoring"
+)
+
+// readinessCacheTTL is how long gates that touch external state reuse their last result.
+const readinessCacheTTL = 5 * time.Second
+
+var (
+	errDraining     = errors.New("shutting down")
+	errWarmupActive = errors.New("warm-up in progress")
+)
+
+// initReadiness registers the gates every instance has. Components add their own
+// (database, migrations, warm-up...) on Bootstrap.Readiness.
+func (b *Bootstrap) initReadiness() {
+	b.Readiness = monitoring.NewReadiness()
+	b.Readiness.Add(monitoring.Gate{
+		Name: "draining",
+		Check: func(context.Context) (monitoring.Detail, error) {
+			if b.draining.Load() {
+				return nil, errDraining
+			}
+			return nil, nil
+		},
+	})
+	b.Readiness.Add(monitoring.Gate{
+		Name: "warmup",
+		Check: func(context.Context) (monitoring.Detail, error) {
+			if !b.Warmup.Done() {
+				return nil, errWarmupActive
+			}
+			return nil, nil
+		},
+	})
+	b.Readiness.Add(monitoring.Gate{
+		Name:     "data-dir",
+		Check:    dataDirWritable,
+		CacheFor: readinessCacheTTL,
+	})
+}
+
+// dataDirWritable creates and removes a temporary file in the data directory.
+func dataDirWritable(context.Context) (monitoring.Detail, error) {
+	dir := configuration.Get().AppDataDir
+	detail := monitoring.Detail{"dir": dir}
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return detail, fmt.Errorf("data dir not writable: %w", err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	return detail, os.Remove(name)
+}
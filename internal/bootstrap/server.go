@@ -0,0 +1,142 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+)
+
+// server is one http.Server and the listeners it accepts on.
+type server struct {
+	name string // "public" or "admin", used in logs and errors
+	srv  *http.Server
+	lns  []net.Listener
+	// tls is decided up front: Serve fills srv.TLSConfig in when it sets up HTTP/2.
+	tls bool
+}
+
+func (s *server) serve(ln net.Listener) error {
+	var err error
+	if s.tls {
+		err = s.srv.ServeTLS(ln, "", "") // certificates come from TLSConfig
+	} else {
+		err = s.srv.Serve(ln)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve %s on %s: %w", s.name, ln.Addr(), err)
+	}
+	return nil
+}
+
+func (s *server) close() {
+	for _, ln := range s.lns {
+		_ = ln.Close()
+	}
+}
+
+// listen opens the listeners of the public server (TCP, unix socket, systemd sockets) and,
+// unless rest.single_port is set, of the admin one. Nothing stays open when it fails.
+func (b *Bootstrap) listen() (servers []*server, err error) {
+	rc := b.Config.RestConfig
+	public := &server{name: "public", srv: newHTTPServer(rc, b.Router)}
+	if b.certs != nil {
+		public.srv.TLSConfig = b.certs.tlsConfig()
+		public.tls = true
+	}
+	servers = append(servers, public)
+	var admin *server
+	if b.AdminRouter != nil {
+		admin = &server{name: "admin", srv: newHTTPServer(rc, b.AdminRouter)}
+		servers = append(servers, admin)
+	}
+	if b.Notifications != nil {
+		// Shutdown does not wait for streams to end by themselves: close them as draining starts.
+		for _, s := range servers {
+			s.srv.RegisterOnShutdown(b.Notifications.Close)
+		}
+	}
+	defer func() {
+		if err != nil {
+			for _, s := range servers {
+				s.close()
+			}
+		}
+	}()
+
+	activated, err := systemdListeners()
+	if err != nil {
+		return nil, err
+	}
+	for name, lns := range activated {
+		if name == adminSocketName && admin != nil {
+			admin.lns = append(admin.lns, lns...)
+		} else {
+			public.lns = append(public.lns, lns...)
+		}
+	}
+
+	if rc.TCP {
+		addr := net.JoinHostPort(rc.Host, strconv.Itoa(rc.Port))
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("listen public on %s: %w", addr, err)
+		}
+		public.lns = append(public.lns, ln)
+	}
+	if rc.UnixSocket != "" {
+		ln, err := listenUnix(rc)
+		if err != nil {
+			return nil, fmt.Errorf("listen public on %s: %w", rc.UnixSocket, err)
+		}
+		public.lns = append(public.lns, ln)
+	}
+	if admin != nil && len(admin.lns) == 0 {
+		addr := net.JoinHostPort(rc.AdminHost, strconv.Itoa(rc.AdminPort))
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("listen admin on %s: %w", addr, err)
+		}
+		admin.lns = append(admin.lns, ln)
+	}
+
+	if len(public.lns) == 0 {
+		return nil, errors.New("no public listener: enable rest.tcp, set rest.unix_socket or pass systemd sockets")
+	}
+	return servers, nil
+}
+
+// newHTTPServer applies the REST timeouts; they are shared by the public and admin servers.
+func newHTTPServer(rc *configuration.RestConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadTimeout:       rc.ReadTimeout,
+		ReadHeaderTimeout: rc.ReadHeaderTimeout,
+		WriteTimeout:      rc.WriteTimeout,
+		IdleTimeout:       rc.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+}
+
+// shutdown drains every server concurrently within ctx.
+func shutdown(ctx context.Context, servers []*server) error {
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.srv.Shutdown(ctx); err != nil {
+				errs[i] = fmt.Errorf("drain %s connections: %w", s.name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
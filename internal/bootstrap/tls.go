@@ -0,0 +1,168 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+	"github.com/khedhrije/tools-archetype/pkg/monitoring"
+)
+
+// certStore holds the public listener's certificate and client CA bundle. It reads
+// the files again on every tick of the reload loop and swaps them when they changed,
+// so rotations (cert-manager renewals) apply to new handshakes without a restart.
+type certStore struct {
+	cfg configuration.TLSConfig
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	sum       [sha256.Size]byte // of the files' contents, to detect changes
+}
+
+// newCertStore loads the files once; an error here is a startup failure.
+func newCertStore(cfg configuration.TLSConfig) (*certStore, error) {
+	s := &certStore{cfg: cfg}
+	if _, err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reload reads the files and swaps them in when their contents changed.
+func (s *certStore) reload() (changed bool, err error) {
+	certPEM, err := os.ReadFile(s.cfg.CertFile)
+	if err != nil {
+		return false, fmt.Errorf("read certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(s.cfg.KeyFile)
+	if err != nil {
+		return false, fmt.Errorf("read private key: %w", err)
+	}
+	var caPEM []byte
+	if s.cfg.ClientCAFile != "" {
+		if caPEM, err = os.ReadFile(s.cfg.ClientCAFile); err != nil {
+			return false, fmt.Errorf("read client CA bundle: %w", err)
+		}
+	}
+
+	sum := sha256.Sum256(bytes.Join([][]byte{certPEM, keyPEM, caPEM}, []byte{0}))
+	s.mu.RLock()
+	unchanged := s.cert != nil && sum == s.sum
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	// Files are often rewritten one at a time: a mismatched pair fails here and the
+	// previous certificate stays in use until the next tick.
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("load key pair: %w", err)
+	}
+	var pool *x509.CertPool
+	if caPEM != nil {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return false, errors.New("client CA bundle contains no PEM certificate")
+		}
+	}
+
+	s.mu.Lock()
+	s.cert, s.clientCAs, s.sum = &cert, pool, sum
+	s.mu.Unlock()
+	return true, nil
+}
+
+// tlsConfig resolves the certificate and client CAs per handshake.
+func (s *certStore) tlsConfig() *tls.Config {
+	clientAuth := tls.NoClientCert
+	if s.cfg.ClientCAFile != "" {
+		clientAuth = tls.RequireAndVerifyClientCert
+		if s.cfg.ClientAuth == "verify-if-given" {
+			clientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	base := &tls.Config{MinVersion: tls.VersionTLS12}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			NextProtos:   []string{"h2", "http/1.1"},
+			Certificates: []tls.Certificate{*s.cert},
+			ClientCAs:    s.clientCAs,
+			ClientAuth:   clientAuth,
+		}, nil
+	}
+	return base
+}
+
+// component reloads the files every rest.tls.reload_interval, with a watchdog heartbeat.
+func (s *certStore) component(w *monitoring.Watchdog) Component {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	return Component{
+		Name: "tls-certificates",
+		Start: func(context.Context) error {
+			hb := w.Register("tls-certificates", s.cfg.ReloadInterval)
+			go func() {
+				defer close(done)
+				defer hb.Stop()
+				ticker := time.NewTicker(s.cfg.ReloadInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-stop:
+						return
+					case <-ticker.C:
+					}
+					hb.Beat()
+					changed, err := s.reload()
+					switch {
+					case err != nil:
+						slog.Warn("TLS certificate reload failed, keeping the current one", "error", err)
+					case changed:
+						if certs := s.certificates(); len(certs) > 0 {
+							slog.Info("TLS certificate reloaded", "subject", certs[0].Subject, "notAfter", certs[0].NotAfter)
+						}
+					}
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			close(stop)
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// certificates describes the served certificate chain for ServerInfo.
+func (s *certStore) certificates() []monitoring.Certificate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []monitoring.Certificate
+	for _, der := range s.cert.Certificate {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			continue
+		}
+		out = append(out, monitoring.CertificateOf(c))
+	}
+	return out
+}
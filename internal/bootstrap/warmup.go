@@ -0,0 +1,189 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/khedhrije/tools-archetype/pkg/monitoring"
+)
+
+// Retry backoff of failed warm-up tasks: doubles from warmupBackoffMin up to warmupBackoffMax, with jitter.
+const (
+	warmupBackoffMin = 500 * time.Millisecond
+	warmupBackoffMax = 30 * time.Second
+	// defaultTaskTimeout bounds one attempt of a task that does not set its own timeout.
+	defaultTaskTimeout = time.Minute
+)
+
+// TaskState is the progress of a warm-up task.
+type TaskState string
+
+const (
+	TaskPending  TaskState = "pending"
+	TaskRunning  TaskState = "running"
+	TaskRetrying TaskState = "retrying" // last attempt failed, waiting for the next one
+	TaskDone     TaskState = "done"
+	TaskFailed   TaskState = "failed" // MaxAttempts reached
+)
+
+// Task is a one-shot job to complete before the service is considered started:
+// warming a cache, applying migrations, priming a connection pool...
+type Task struct {
+	Name        string
+	Run         func(ctx context.Context) error
+	Timeout     time.Duration // per attempt, defaultTaskTimeout when zero
+	MaxAttempts int           // 0 retries until success or shutdown
+}
+
+type task struct {
+	Task
+	state     TaskState
+	attempts  int
+	err       error
+	startedAt time.Time
+	doneAt    time.Time
+	nextRetry time.Time
+}
+
+// Warmup runs the registered tasks once the lifecycle components are started,
+// retrying failures with exponential backoff. It backs /api/startupz.
+type Warmup struct {
+	mu      sync.Mutex
+	tasks   []*task
+	started bool // Run was called: the task list is final
+}
+
+func NewWarmup() *Warmup {
+	return &Warmup{}
+}
+
+// Add registers a task. A task added once Run has started is logged and dropped: it would never
+// run, and counting it would keep the service from ever being started.
+func (w *Warmup) Add(t Task) {
+	if t.Timeout <= 0 {
+		t.Timeout = defaultTaskTimeout
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started {
+		slog.Error("warm-up task added after the warm-up started, ignored", "task", t.Name)
+		return
+	}
+	w.tasks = append(w.tasks, &task{Task: t, state: TaskPending})
+}
+
+// Run runs every task concurrently and returns once they are all done or failed,
+// or ctx is canceled. The returned error joins the errors of the failed tasks.
+func (w *Warmup) Run(ctx context.Context) error {
+	w.mu.Lock()
+	w.started = true
+	tasks := append([]*task(nil), w.tasks...)
+	w.mu.Unlock()
+
+	start := time.Now()
+	errs := make([]error, len(tasks))
+	var wg sync.WaitGroup
+	for i, t := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = w.run(ctx, t)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	if len(tasks) > 0 {
+		slog.Info("warm-up complete", "tasks", len(tasks), "took", time.Since(start))
+	}
+	return nil
+}
+
+func (w *Warmup) run(ctx context.Context, t *task) error {
+	backoff := warmupBackoffMin
+	for {
+		w.update(t, func() {
+			t.state = TaskRunning
+			t.attempts++
+			if t.startedAt.IsZero() {
+				t.startedAt = time.Now()
+			}
+		})
+
+		attemptCtx, cancel := context.WithTimeout(ctx, t.Timeout)
+		err := t.Run(attemptCtx)
+		cancel()
+
+		if err == nil {
+			w.update(t, func() { t.state, t.err, t.doneAt = TaskDone, nil, time.Now() })
+			slog.Info("warm-up task done", "task", t.Name, "attempts", t.attempts)
+			return nil
+		}
+		if t.MaxAttempts > 0 && t.attempts >= t.MaxAttempts {
+			w.update(t, func() { t.state, t.err, t.doneAt = TaskFailed, err, time.Now() })
+			slog.Error("warm-up task failed", "task", t.Name, "attempts", t.attempts, "error", err)
+			return fmt.Errorf("warm-up %s: %w", t.Name, err)
+		}
+
+		// Jitter keeps replicas from retrying against a shared dependency in lockstep.
+		wait := backoff/2 + rand.N(backoff/2+1)
+		w.update(t, func() { t.state, t.err, t.nextRetry = TaskRetrying, err, time.Now().Add(wait) })
+		slog.Warn("warm-up task failed, retrying", "task", t.Name, "attempt", t.attempts, "retryIn", wait, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("warm-up %s: %w", t.Name, ctx.Err())
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, warmupBackoffMax)
+	}
+}
+
+func (w *Warmup) update(t *task, fn func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fn()
+}
+
+// Status reports every task in registration order.
+func (w *Warmup) Status() []monitoring.Task {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]monitoring.Task, 0, len(w.tasks))
+	for _, t := range w.tasks {
+		st := monitoring.Task{
+			Name:       t.Name,
+			State:      string(t.state),
+			Attempts:   t.attempts,
+			StartedAt:  t.startedAt,
+			FinishedAt: t.doneAt,
+		}
+		if t.err != nil {
+			st.Error = t.err.Error()
+		}
+		if t.state == TaskRetrying {
+			st.NextRetry = t.nextRetry
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// Done reports whether every task completed successfully.
+func (w *Warmup) Done() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, t := range w.tasks {
+		if t.state != TaskDone {
+			return false
+		}
+	}
+	return true
+}
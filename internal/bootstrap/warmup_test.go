@@ -0,0 +1,57 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWarmup(t *testing.T) {
+	tests := []struct {
+		name     string
+		tasks    []Task
+		wantErr  bool
+		wantDone bool
+	}{
+		{name: "no task", wantDone: true},
+		{
+			name:     "every task succeeds",
+			tasks:    []Task{{Name: "a", Run: func(context.Context) error { return nil }}, {Name: "b", Run: func(context.Context) error { return nil }}},
+			wantDone: true,
+		},
+		{
+			name:    "a task exhausts its attempts",
+			tasks:   []Task{{Name: "a", Run: func(context.Context) error { return errors.New("boom") }, MaxAttempts: 1}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWarmup()
+			for _, task := range tt.tasks {
+				w.Add(task)
+			}
+			if err := w.Run(context.Background()); (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, want an error: %v", err, tt.wantErr)
+			}
+			if w.Done() != tt.wantDone {
+				t.Errorf("Done() = %v, want %v", w.Done(), tt.wantDone)
+			}
+		})
+	}
+}
+
+func TestWarmupAddAfterRun(t *testing.T) {
+	w := NewWarmup()
+	w.Add(Task{Name: "early", Run: func(context.Context) error { return nil }})
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	w.Add(Task{Name: "late", Run: func(context.Context) error { return nil }})
+	if !w.Done() {
+		t.Error("a task added after Run keeps the warm-up from being done")
+	}
+	if st := w.Status(); len(st) != 1 || st[0].Name != "early" {
+		t.Errorf("Status() = %+v, want only the early task", st)
+	}
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/khedhrije/tools-archetype/pkg/monitoring"
+)
+
+func check(args []string) int {
+	usage := "<name> [--output json|table] [flags]\n\nChecks: " + strings.Join(monitoring.CheckNames(), ", ")
+	fs := flagSet("check", usage, true)
+	output := fs.StringP("output", "o", "table", "output format: json | table")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 || (*output != "json" && *output != "table") {
+		fs.Usage()
+		return exitUsage
+	}
+	chk, found := monitoring.Checks()[fs.Arg(0)]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown check %q, available: %s\n", fs.Arg(0), strings.Join(monitoring.CheckNames(), ", "))
+		return exitUsage
+	}
+	if _, ok := loadConfig(fs); !ok {
+		return exitFailure
+	}
+
+	start := time.Now()
+	detail, err := chk.RunOnce(context.Background())
+	latency := time.Since(start).Milliseconds()
+
+	code := exitOK
+	status, errKey := "ok", "error"
+	switch {
+	case errors.Is(err, monitoring.ErrDegraded):
+		status, errKey = "degraded", "warning"
+	case err != nil:
+		code, status = exitFailure, "error"
+	}
+
+	if *output == "json" {
+		// Same shape as the /api/check endpoints
+		res := map[string]any{"status": status, "name": chk.Name, "latencyMs": latency, "detail": detail}
+		if err != nil {
+			res[errKey] = err.Error()
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(res)
+		return code
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "check\t%s\nstatus\t%s\nlatency\t%dms\n", chk.Name, status, latency)
+	if err != nil {
+		fmt.Fprintf(tw, "%s\t%s\n", errKey, err)
+	}
+	rows := map[string]string{}
+	flatten("", detail, rows)
+	keys := make([]string, 0, len(rows))
+	for k := range rows {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\n", k, rows[k])
+	}
+	_ = tw.Flush()
+	return code
+}
+
+// flatten turns nested detail values into dotted keys ("targets.0.latencyMs").
+func flatten(prefix string, v any, out map[string]string) {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+	switch val := v.(type) {
+	case monitoring.Detail:
+		flatten(prefix, map[string]any(val), out)
+	case map[string]any:
+		for k, sub := range val {
+			flatten(join(k), sub, out)
+		}
+	case []any:
+		for i, sub := range val {
+			flatten(join(fmt.Sprint(i)), sub, out)
+		}
+	default:
+		// Structs and typed slices: go through JSON to reach their fields
+		if raw, err := json.Marshal(val); err == nil && len(raw) > 0 && (raw[0] == '{' || raw[0] == '[') {
+			var generic any
+			if json.Unmarshal(raw, &generic) == nil {
+				flatten(prefix, generic, out)
+				return
+			}
+		}
+		out[prefix] = fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,123 @@
+// Package cli dispatches the server binary's subcommands.
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+	"github.com/spf13/pflag"
+)
+
+// Exit codes of the commands other than serve (see bootstrap.ExitCode for serve).
+const (
+	exitOK      = 0
+	exitFailure = 1 // the check failed, the configuration is invalid, the server is unhealthy...
+	exitUsage   = 2
+)
+
+// command is one subcommand. run receives the arguments after the command name.
+type command struct {
+	name    string
+	usage   string // arguments, shown after the name in the help
+	summary string
+	run     func(args []string) int
+}
+
+// commands is filled in init to break the reference cycle with printUsage.
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "serve", usage: "[flags]", summary: "start the HTTP server (default command)", run: serve},
+		{name: "healthcheck", usage: "[--url URL] [--timeout D] [flags]", summary: "probe the local server's /api/healthz, exit 1 unless it answers 2xx", run: healthcheck},
+		{name: "config", usage: "print|validate [flags]", summary: "print the effective configuration (secrets masked) or validate it", run: configCmd},
+		{name: "version", usage: "[--json]", summary: "print the build information", run: version},
+		{name: "migrate", usage: "up|down [N]|to <version>|status [flags]", summary: "apply, roll back or list the embedded schema migrations", run: migrateCmd},
+		{name: "check", usage: "<name> [--output json|table] [flags]", summary: "run one monitoring check once and print its detail", run: check},
+	}
+}
+
+// Run executes the command named by args[0] and returns the process exit code.
+// Without a command, or when args start with a flag, it serves, so `server --rest-port 9090` keeps working.
+func Run(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help" {
+		return serve(args)
+	}
+	switch args[0] {
+	case "help", "-h", "--help":
+		printUsage(os.Stdout)
+		return exitOK
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	printUsage(os.Stderr)
+	return exitUsage
+}
+
+func printUsage(w io.Writer) {
+	bin := filepath.Base(os.Args[0])
+	fmt.Fprintf(w, "Usage: %s <command> [arguments]\n\nCommands:\n", bin)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> --help' for the flags of a command.\n", bin)
+}
+
+// flagSet returns the flags of a command; withConfig adds the configuration flags.
+func flagSet(name, usage string, withConfig bool) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SortFlags = false
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s %s\n\nFlags:\n%s", filepath.Base(os.Args[0]), name, usage, fs.FlagUsages())
+	}
+	if withConfig {
+		configuration.RegisterFlags(fs)
+	}
+	return fs
+}
+
+// parse parses args and reports the exit code to use when the command must stop there.
+func parse(fs *pflag.FlagSet, args []string) (code int, ok bool) {
+	err := fs.Parse(args)
+	switch {
+	case err == nil:
+		return exitOK, true
+	case errors.Is(err, pflag.ErrHelp):
+		return exitOK, false
+	default:
+		return exitUsage, false
+	}
+}
+
+// loadConfig loads the configuration from the parsed flags, reporting errors on stderr.
+func loadConfig(fs *pflag.FlagSet) (*configuration.Snapshot, bool) {
+	snap, err := configuration.LoadFlags(fs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to load configuration: %v\n", err)
+		return nil, false
+	}
+	return snap, true
+}
+
+// validateSettings validates cfg for a CI check, reporting on stderr. The checks of this host
+// (data_dir) are only warnings: it is seldom the host the service runs on.
+func validateSettings(cfg *configuration.AppConfig) bool {
+	if err := cfg.ValidateSettings(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	for _, p := range cfg.HostProblems() {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", p)
+	}
+	fmt.Println("configuration is valid")
+	return true
+}
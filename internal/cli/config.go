@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+)
+
+func configCmd(args []string) int {
+	const usage = "print|validate [flags]"
+	if len(args) == 0 || (args[0] != "print" && args[0] != "validate") {
+		fmt.Fprintf(os.Stderr, "Usage: config %s\n", usage)
+		return exitUsage
+	}
+	action := args[0]
+	fs := flagSet("config "+action, "[flags]", true)
+	if code, ok := parse(fs, args[1:]); !ok {
+		return code
+	}
+	snap, ok := loadConfig(fs)
+	if !ok {
+		return exitFailure
+	}
+
+	switch action {
+	case "print":
+		// Same document as GET /api/config: values, their sources, secrets masked
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(configuration.Describe()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+	case "validate":
+		if !validateSettings(snap.Config) {
+			return exitFailure
+		}
+	}
+	return exitOK
+}
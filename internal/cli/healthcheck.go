@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+)
+
+// healthcheck is meant for Docker HEALTHCHECK: the runtime image has no curl.
+func healthcheck(args []string) int {
+	fs := flagSet("healthcheck", "[--url URL] [--timeout D] [--cacert F] [--cert F --key F] [flags]", true)
+	url := fs.String("url", "", "URL to probe (default: /api/healthz on the admin port, or the REST port with rest.single_port)")
+	timeout := fs.Duration("timeout", 3*time.Second, "request timeout")
+	caFile := fs.String("cacert", "", "PEM CA bundle verifying an HTTPS server (default: the local listener is not verified)")
+	certFile := fs.String("cert", "", "PEM client certificate for mutual TLS, required when the local listener requires one")
+	keyFile := fs.String("key", "", "PEM private key of --cert")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	transport := &http.Transport{TLSClientConfig: tlsCfg}
+	if *url == "" {
+		if _, ok := loadConfig(fs); !ok {
+			return exitFailure
+		}
+		rc := configuration.Get().RestConfig
+		scheme := "http"
+		// TLS only serves the public listener, which hosts the technical routes with single_port.
+		if rc.SinglePort && rc.TLS.Enabled() {
+			scheme = "https"
+			// The certificate names the public host, not loopback: the probe reaches its own listener.
+			tlsCfg.InsecureSkipVerify = *caFile == ""
+			// The server's own certificate is no client certificate: it seldom chains to the client CA
+			// or allows client authentication.
+			if *certFile == "" && rc.TLS.ClientCAFile != "" && rc.TLS.ClientAuth != "verify-if-given" {
+				fmt.Fprintln(os.Stderr, "unhealthy: the listener requires a client certificate: pass --cert and --key")
+				return exitFailure
+			}
+		}
+		addr, err := localAddr(rc)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unhealthy: %v\n", err)
+			return exitFailure
+		}
+		if rc.SinglePort && !rc.TCP {
+			// The public routes are only served on the unix socket: dial it, whatever the URL host.
+			socket := rc.UnixSocket
+			transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			}
+		}
+		*url = scheme + "://" + addr + "/api/healthz"
+	}
+	if err := clientTLS(tlsCfg, *caFile, *certFile, *keyFile); err != nil {
+		fmt.Fprintf(os.Stderr, "unhealthy: %v\n", err)
+		return exitFailure
+	}
+
+	client := &http.Client{Timeout: *timeout, Transport: transport}
+	resp, err := client.Get(*url)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unhealthy: %v\n", err)
+		return exitFailure
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		fmt.Fprintf(os.Stderr, "unhealthy: %s answered %s\n", *url, resp.Status)
+		return exitFailure
+	}
+	fmt.Printf("healthy: %s answered %s\n", *url, resp.Status)
+	return exitOK
+}
+
+// clientTLS adds the CA bundle verifying the server and the client certificate, when set, to cfg.
+func clientTLS(cfg *tls.Config, caFile, certFile, keyFile string) error {
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("read CA bundle: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate in %s", caFile)
+		}
+	}
+	if (certFile == "") != (keyFile == "") {
+		return errors.New("--cert and --key go together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return nil
+}
+
+// localAddr is where this host reaches the technical routes: the admin listener unless
+// rest.single_port is set, on loopback when it listens on every interface. Without rest.tcp, the
+// public routes are reached on rest.unix_socket, and "localhost" only names the host of the URL.
+func localAddr(rc *configuration.RestConfig) (string, error) {
+	if !rc.SinglePort {
+		return hostPort(rc.AdminHost, rc.AdminPort), nil
+	}
+	if rc.TCP {
+		return hostPort(rc.Host, rc.Port), nil
+	}
+	if rc.UnixSocket == "" {
+		return "", errors.New("no TCP listener nor unix socket to probe (systemd sockets): pass --url")
+	}
+	return "localhost", nil
+}
+
+func hostPort(host string, port int) string {
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
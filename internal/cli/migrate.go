@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres"
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres/migrate"
+	"github.com/khedhrije/tools-archetype/migrations"
+)
+
+func migrateCmd(args []string) int {
+	const usage = "up|down [N]|to <version>|status [flags]"
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: migrate %s\n", usage)
+		return exitUsage
+	}
+	action := args[0]
+	maxArgs, actionUsage := 0, "[flags]"
+	switch action {
+	case "up", "status":
+	case "down":
+		maxArgs, actionUsage = 1, "[N] [flags]" // 1 by default
+	case "to":
+		maxArgs, actionUsage = 1, "<version> [flags]"
+	default:
+		fmt.Fprintf(os.Stderr, "Usage: migrate %s\n", usage)
+		return exitUsage
+	}
+
+	fs := flagSet("migrate "+action, actionUsage, true)
+	output := fs.StringP("output", "o", "table", "status output format: json | table")
+	if code, ok := parse(fs, args[1:]); !ok {
+		return code
+	}
+	if fs.NArg() > maxArgs || (action == "to" && fs.NArg() == 0) || (*output != "json" && *output != "table") {
+		fs.Usage()
+		return exitUsage
+	}
+	var n int64
+	if fs.NArg() == 1 {
+		v, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+		if err != nil || v < 0 || (action == "down" && v == 0) {
+			fmt.Fprintf(os.Stderr, "invalid %s argument %q\n", action, fs.Arg(0))
+			return exitUsage
+		}
+		n = v
+	}
+	snap, ok := loadConfig(fs)
+	if !ok {
+		return exitFailure
+	}
+	// Migrating does not need data_dir.
+	if err := snap.Config.ValidateSettings(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	// Interrupting releases the lock: the running migration's transaction is rolled back.
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	pool, err := postgres.NewPool(ctx, snap.Config.DatabaseConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "database: %v\n", err)
+		return exitFailure
+	}
+	defer pool.Close()
+	m, err := migrations.New(pool)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	switch action {
+	case "up":
+		err = m.Up(ctx)
+	case "down":
+		err = m.Down(ctx, int(max(n, 1)))
+	case "to":
+		err = m.To(ctx, n)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	st, err := m.Status(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	printMigrations(st, *output)
+	// status fails like the readiness gate; after up/down/to the schema is where it was asked to be
+	if err := st.Err(); action == "status" && err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func printMigrations(st migrate.Status, output string) {
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(st)
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "current\t%d\nlatest\t%d\npending\t%d\ndrifted\t%d\n\n", st.Current, st.Latest, st.Pending, st.Drifted)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED AT\tDURATION")
+	for _, e := range st.Migrations {
+		appliedAt := "-"
+		if !e.AppliedAt.IsZero() {
+			appliedAt = e.AppliedAt.UTC().Format("2006-01-02 15:04:05Z")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", e.Version, e.Name, e.State, appliedAt, e.Duration)
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"log/slog"
+
+	"github.com/khedhrije/tools-archetype/internal/bootstrap"
+)
+
+func serve(args []string) int {
+	fs := flagSet("serve", "[flags]", true)
+	dryRun := fs.Bool("dry-run", false, "validate the configuration, print the report and exit")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	snap, ok := loadConfig(fs)
+	if !ok {
+		return bootstrap.ExitStartupFailure
+	}
+	if *dryRun {
+		if !validateSettings(snap.Config) {
+			return bootstrap.ExitStartupFailure
+		}
+		return bootstrap.ExitOK
+	}
+
+	app, err := bootstrap.InitBootstrap()
+	if err == nil {
+		err = app.Run()
+	}
+	if err != nil {
+		slog.Error("service stopped with an error", "error", err)
+	}
+	return bootstrap.ExitCode(err)
+}
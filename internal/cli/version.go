@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+	"github.com/khedhrije/tools-archetype/pkg/buildinfo"
+)
+
+func version(args []string) int {
+	fs := flagSet("version", "[--json] [flags]", true)
+	asJSON := fs.Bool("json", false, "print the full build information, dependencies included, as JSON")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+
+	// The configuration only supplies fallbacks: an invalid one must not hide the version.
+	var fallback buildinfo.Info
+	if snap, err := configuration.LoadFlags(fs); err == nil {
+		fallback = buildinfo.Info{
+			Version:  snap.Config.AppVersion,
+			Revision: snap.Config.AppRevision,
+			BuiltAt:  snap.Config.AppBuiltAt,
+		}
+	}
+	info := buildinfo.Get(fallback)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(info)
+		return exitOK
+	}
+	dirty := ""
+	if info.Dirty {
+		dirty = " (dirty)"
+	}
+	fmt.Printf("version:  %s\nrevision: %s%s\nbuilt:    %s\ngo:       %s %s/%s\n",
+		info.Version, info.Revision, dirty, info.BuiltAt, info.GoVersion, info.GOOS, info.GOARCH)
+	return exitOK
+}
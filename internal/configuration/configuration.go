@@ -1,65 +1,194 @@
 package configuration
 
-import (
-	"github.com/spf13/viper"
-)
+import "time"
 
-var Config *AppConfig
-
-func init() {
-	Config = loadFromEnv()
+type AppConfig struct {
+	AppName        string          `mapstructure:"name"`
+	AppVersion     string          `mapstructure:"version"`
+	AppRevision    string          `mapstructure:"revision"`
+	AppBuiltAt     string          `mapstructure:"built_at"`
+	AppDataDir     string          `mapstructure:"data_dir"`
+	Env            string          `mapstructure:"env"`
+	LogConfig      *LogConfig      `mapstructure:"log"`
+	RestConfig     *RestConfig     `mapstructure:"rest"`
+	DatabaseConfig *DatabaseConfig `mapstructure:"db"`
+	ChecksConfig   *ChecksConfig   `mapstructure:"checks"`
+	FeaturesConfig *FeaturesConfig `mapstructure:"features"`
+	LivenessConfig *LivenessConfig `mapstructure:"liveness"`
 }
 
-type AppConfig struct {
-	AppName        string
-	AppVersion     string
-	AppRevision    string
-	AppBuiltAt     string
-	AppDataDir     string
-	Env            string
-	RestConfig     *RestConfig
-	DatabaseConfig *DatabaseConfig
+type LogConfig struct {
+	Level string `mapstructure:"level"` // debug | info | warn | error
 }
 
 type RestConfig struct {
-	Host string
-	Port int
+	Host string `mapstructure:"host"`
+	Port int    `mapstructure:"port"`
+	// Technical routes (/api/livez, /api/data, ...) and the monitoring SPA are served on the
+	// admin listener, kept off the ingress. SinglePort serves everything on Host:Port instead.
+	AdminHost  string `mapstructure:"admin_host"`
+	AdminPort  int    `mapstructure:"admin_port"`
+	SinglePort bool   `mapstructure:"single_port"`
+	// TCP listens on Host:Port; disable it when the public listeners all come from
+	// UnixSocket or systemd socket activation (LISTEN_FDS).
+	TCP             bool   `mapstructure:"tcp"`
+	UnixSocket      string `mapstructure:"unix_socket"`       // also serve the public routes on this socket path
+	UnixSocketMode  string `mapstructure:"unix_socket_mode"`  // octal file mode, e.g. "0660"
+	UnixSocketOwner string `mapstructure:"unix_socket_owner"` // "user:group" or "uid:gid", empty keeps the process's
+
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+	// PreStopDelay keeps serving (with readiness failing) so load balancers stop routing before draining.
+	PreStopDelay    time.Duration `mapstructure:"pre_stop_delay"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // deadline to drain in-flight requests
+
+	TLS TLSConfig `mapstructure:"tls"` // applies to the public listener
+}
+
+// TLSConfig enables HTTPS when CertFile and KeyFile are set, and mutual TLS when ClientCAFile is set too.
+// The files are read again every ReloadInterval so renewed certificates are picked up without a restart.
+type TLSConfig struct {
+	CertFile       string        `mapstructure:"cert_file"`
+	KeyFile        string        `mapstructure:"key_file"`
+	ClientCAFile   string        `mapstructure:"client_ca_file"`
+	ClientAuth     string        `mapstructure:"client_auth"` // require | verify-if-given
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+}
+
+// Enabled reports whether HTTPS is configured.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// LivenessConfig sets the ceilings above which /api/livez fails (0 disables a ceiling).
+type LivenessConfig struct {
+	MaxGoroutines int `mapstructure:"max_goroutines"`
+	MaxMemoryMB   int `mapstructure:"max_memory_mb"` // memory obtained from the OS by the Go runtime
 }
 
 type DatabaseConfig struct {
-	DSN      string
-	Name     string
-	Host     string
-	Port     int
-	Username string
-	Password string
-	SSL      string // disable | require | verify-ca | verify-full
-	Addr     string
-}
-
-func loadFromEnv() *AppConfig {
-	viper.AutomaticEnv()
-
-	return &AppConfig{
-		AppName:     viper.GetString("APP_NAME"),
-		AppVersion:  viper.GetString("APP_VERSION"),
-		Env:         viper.GetString("APP_ENV"),
-		AppDataDir:  viper.GetString("APP_DATA_DIR"),
-		AppRevision: viper.GetString("APP_REVISION"),
-		AppBuiltAt:  viper.GetString("APP_BUILT_AT"),
-		RestConfig: &RestConfig{
-			Host: viper.GetString("APP_REST_HOST"),
-			Port: viper.GetInt("APP_REST_PORT"),
-		},
-		DatabaseConfig: &DatabaseConfig{
-			DSN:      viper.GetString("APP_DB__DSN"),
-			Name:     viper.GetString("APP_DB__NAME"),
-			Host:     viper.GetString("APP_DB__HOST"),
-			Port:     viper.GetInt("APP_DB__PORT"),
-			Username: viper.GetString("APP_DB__USERNAME"),
-			Password: viper.GetString("APP_DB__PASSWORD"),
-			SSL:      viper.GetString("APP_DB_SSL"),
-			Addr:     viper.GetString("APP_DB_ADDR"),
-		},
-	}
+	DSN      DSN               `mapstructure:"dsn" secret:"dsn"`
+	Name     string            `mapstructure:"name"`
+	Host     string            `mapstructure:"host"`
+	Port     int               `mapstructure:"port"`
+	Username string            `mapstructure:"username"`
+	Password Secret            `mapstructure:"password" secret:"true"`
+	SSL      string            `mapstructure:"ssl"` // disable | require | verify-ca | verify-full
+	Addr     string            `mapstructure:"addr"`
+	Role     string            `mapstructure:"role"` // primary | replica, verified like DatabaseTargetConfig.Role; empty skips it
+	TLS      DatabaseTLSConfig `mapstructure:"tls"`  // files and TLS parameters going with SSL
+
+	// StatementTimeout is set on every pooled connection (0 keeps the server's default).
+	StatementTimeout time.Duration     `mapstructure:"statement_timeout"`
+	Pool             PoolConfig        `mapstructure:"pool"`
+	Migrations       MigrationsConfig  `mapstructure:"migrations"`
+	Diagnostics      DiagnosticsConfig `mapstructure:"diagnostics"`
+	Trace            TraceConfig       `mapstructure:"trace"`
+	Notify           NotifyConfig      `mapstructure:"notify"`
+
+	// Targets are the databases probed by the database check besides this one (named PrimaryTarget),
+	// keyed by name: read replicas, an analytics database, ...
+	Targets map[string]DatabaseTargetConfig `mapstructure:"targets"`
+}
+
+// PrimaryTarget names the application's database among the database check targets.
+const PrimaryTarget = "primary"
+
+// DatabaseTargetConfig is one more database probed by the database check. It connects with
+// the db.tls and db.statement_timeout settings, through a pool opened for each check.
+type DatabaseTargetConfig struct {
+	DSN  DSN    `mapstructure:"dsn" secret:"dsn"`
+	Role string `mapstructure:"role"` // primary | replica, verified against pg_is_in_recovery(); empty skips it
+	// Critical targets fail the check when they fail, the others only degrade it (nil means true).
+	Critical *bool `mapstructure:"critical"`
+}
+
+// IsCritical reports whether the target failing fails the database check.
+func (t DatabaseTargetConfig) IsCritical() bool {
+	return t.Critical == nil || *t.Critical
+}
+
+// DiagnosticsConfig holds the thresholds of /api/check/database?deep=true. Reaching a
+// warning threshold reports the check as degraded, a critical one fails it; 0 disables a threshold.
+type DiagnosticsConfig struct {
+	ConnectionsWarningPct  int           `mapstructure:"connections_warning_pct"` // of max_connections
+	ConnectionsCriticalPct int           `mapstructure:"connections_critical_pct"`
+	ReplicaLagWarning      time.Duration `mapstructure:"replica_lag_warning"`
+	ReplicaLagCritical     time.Duration `mapstructure:"replica_lag_critical"`
+	SizeWarningMB          int           `mapstructure:"size_warning_mb"`
+	SizeCriticalMB         int           `mapstructure:"size_critical_mb"`
+	LongQueryWarning       time.Duration `mapstructure:"long_query_warning"` // longest running statement
+	LongQueryCritical      time.Duration `mapstructure:"long_query_critical"`
+	IdleInTxWarning        time.Duration `mapstructure:"idle_in_tx_warning"` // longest idle-in-transaction session
+	IdleInTxCritical       time.Duration `mapstructure:"idle_in_tx_critical"`
+	LockWaitWarning        time.Duration `mapstructure:"lock_wait_warning"` // longest wait on a blocking session
+	LockWaitCritical       time.Duration `mapstructure:"lock_wait_critical"`
+	CacheHitWarningPct     int           `mapstructure:"cache_hit_warning_pct"` // below this
+	CacheHitCriticalPct    int           `mapstructure:"cache_hit_critical_pct"`
+	WraparoundWarningPct   int           `mapstructure:"wraparound_warning_pct"` // of the transaction IDs before wraparound
+	WraparoundCriticalPct  int           `mapstructure:"wraparound_critical_pct"`
+}
+
+// TraceConfig controls the query tracer of the shared pool. Enabled and SlowQueries are read at
+// startup; SlowThreshold follows configuration reloads.
+type TraceConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	SlowThreshold time.Duration `mapstructure:"slow_threshold"` // queries taking longer are logged and kept, 0 disables
+	SlowQueries   int           `mapstructure:"slow_queries"`   // how many of the last slow queries are kept
+}
+
+// NotifyConfig controls the LISTEN/NOTIFY bridge to the server-sent events of /api/events/:channel.
+// It is read at startup.
+type NotifyConfig struct {
+	Channels          []string      `mapstructure:"channels"`            // LISTENed channels, none disables the bridge
+	ClientBuffer      int           `mapstructure:"client_buffer"`       // notifications queued per client before it is dropped
+	MaxReconnectDelay time.Duration `mapstructure:"max_reconnect_delay"` // reconnection backoff ceiling
+}
+
+// MigrationsConfig controls the embedded schema migrations (see the migrations package).
+type MigrationsConfig struct {
+	Auto    bool          `mapstructure:"auto"`    // apply pending migrations at startup, off by default
+	Table   string        `mapstructure:"table"`   // records the applied versions, optionally schema-qualified
+	Timeout time.Duration `mapstructure:"timeout"` // per startup attempt, lock wait included
+}
+
+// DatabaseTLSConfig completes the sslmode of DatabaseConfig.SSL, for the pool and the health checks alike.
+// With a CA file, sslmode require verifies the server certificate chain like verify-ca.
+type DatabaseTLSConfig struct {
+	CAFile     string `mapstructure:"ca_file"`   // sslrootcert: e.g. the managed Postgres provider's CA bundle
+	CertFile   string `mapstructure:"cert_file"` // sslcert and sslkey: client certificate authentication
+	KeyFile    string `mapstructure:"key_file"`
+	ServerName string `mapstructure:"server_name"` // name verified with verify-full and sent as SNI, instead of the host
+	MinVersion string `mapstructure:"min_version"` // 1.2 | 1.3
+}
+
+// PoolConfig sizes the shared connection pool. It is read once at startup: a reload does not resize the pool.
+type PoolConfig struct {
+	MaxConns          int           `mapstructure:"max_conns"`
+	MinConns          int           `mapstructure:"min_conns"`
+	MaxConnLifetime   time.Duration `mapstructure:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `mapstructure:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `mapstructure:"health_check_period"` // how often idle connections are checked
+}
+
+type ChecksConfig struct {
+	ServiceURLs []string `mapstructure:"service_urls"`
+}
+
+type FeaturesConfig struct {
+	TenantHeader string                       `mapstructure:"tenant_header"`
+	Flags        map[string]FeatureFlagConfig `mapstructure:"flags"` // keyed by flag name
+}
+
+// FeatureFlagConfig defines one flag. A request gets the flag when Enabled is true,
+// it matches every targeting rule that is set (Tenants, Headers) and it falls in
+// the Percentage rollout (nil means 100%).
+type FeatureFlagConfig struct {
+	Description string              `mapstructure:"description"`
+	Enabled     bool                `mapstructure:"enabled"`
+	Percentage  *int                `mapstructure:"percentage"`
+	Tenants     []string            `mapstructure:"tenants"`
+	Headers     map[string][]string `mapstructure:"headers"` // header -> accepted values
 }
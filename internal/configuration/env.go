@@ -0,0 +1,77 @@
+package configuration
+
+import (
+	"fmt"
+	"os"
+)
+
+// Deprecation records a value read from a legacy environment variable.
+type Deprecation struct {
+	Key       string `json:"key"`
+	Legacy    string `json:"legacy"`    // variable that was used, e.g. APP_DB__HOST
+	Canonical string `json:"canonical"` // variable to use instead, e.g. APP_DB_HOST
+}
+
+// envValue is one environment variable (or its _FILE variant) found set.
+type envValue struct {
+	name       string
+	value      string
+	secretFile string
+}
+
+// resolveEnv looks up the canonical variable of s and then its legacy aliases.
+// Setting several of them is accepted as long as they agree; the first one wins.
+func resolveEnv(s setting) (resolution, bool, error) {
+	var found []envValue
+	for _, name := range append([]string{s.Env}, s.Aliases...) {
+		ev, ok, err := lookupEnv(name)
+		if err != nil {
+			return resolution{}, false, err
+		}
+		if ok {
+			found = append(found, ev)
+		}
+	}
+	if len(found) == 0 {
+		return resolution{}, false, nil
+	}
+
+	for _, other := range found[1:] {
+		if other.value != found[0].value {
+			// values are left out on purpose: they may be secrets
+			return resolution{}, false, fmt.Errorf("%s: conflicting values in %s and %s", s.Key, found[0].name, other.name)
+		}
+	}
+
+	r := resolution{value: found[0].value, source: SourceEnv}
+	if found[0].secretFile != "" {
+		r.source = SourceSecretFile
+		r.secretFile = found[0].secretFile
+	}
+	for _, ev := range found {
+		if ev.name != s.Env {
+			r.deprecated = &Deprecation{Key: s.Key, Legacy: ev.name, Canonical: s.Env}
+			break
+		}
+	}
+	return r, true, nil
+}
+
+// lookupEnv reads name, or the file named by name_FILE. Empty variables count as unset.
+func lookupEnv(name string) (envValue, bool, error) {
+	v := os.Getenv(name)
+	path := os.Getenv(name + fileSuffix)
+	switch {
+	case v != "" && path != "":
+		return envValue{}, false, fmt.Errorf("%s and %s%s are both set, keep only one", name, name, fileSuffix)
+	case path != "":
+		secret, err := readSecretFile(name, path)
+		if err != nil {
+			return envValue{}, false, err
+		}
+		return envValue{name: name + fileSuffix, value: secret, secretFile: path}, true, nil
+	case v != "":
+		return envValue{name: name, value: v}, true, nil
+	}
+	return envValue{}, false, nil
+}
@@ -0,0 +1,143 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveEnv(t *testing.T) {
+	tests := []struct {
+		name           string
+		canonical      string // APP_DB_HOST
+		alias          string // APP_DB__HOST
+		wantOK         bool
+		wantValue      string
+		wantDeprecated bool
+		wantErr        string
+	}{
+		{name: "unset"},
+		{name: "canonical only", canonical: "db", wantOK: true, wantValue: "db"},
+		{name: "alias only", alias: "legacy", wantOK: true, wantValue: "legacy", wantDeprecated: true},
+		{name: "both equal", canonical: "db", alias: "db", wantOK: true, wantValue: "db", wantDeprecated: true},
+		{name: "both different", canonical: "db", alias: "legacy", wantErr: "db.host: conflicting values in APP_DB_HOST and APP_DB__HOST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_DB_HOST", tt.canonical)
+			t.Setenv("APP_DB__HOST", tt.alias)
+
+			r, ok, err := resolveEnv(settingByKey("db.host"))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("resolveEnv() error = %v, want %q", err, tt.wantErr)
+				}
+				if strings.Contains(err.Error(), "legacy") {
+					t.Errorf("the error leaks a value: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK || (ok && (r.value != tt.wantValue || r.source != SourceEnv)) {
+				t.Errorf("resolveEnv() = %+v, %v; want %q from env: %v", r, ok, tt.wantValue, tt.wantOK)
+			}
+			want := &Deprecation{Key: "db.host", Legacy: "APP_DB__HOST", Canonical: "APP_DB_HOST"}
+			if (r.deprecated != nil) != tt.wantDeprecated || (tt.wantDeprecated && *r.deprecated != *want) {
+				t.Errorf("deprecated = %+v, want one: %v", r.deprecated, tt.wantDeprecated)
+			}
+		})
+	}
+}
+
+func TestResolveEnvSecretFile(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "password")
+	if err := os.WriteFile(secret, []byte("hunter2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantValue  string
+		wantSource Source
+		wantErr    string
+	}{
+		{
+			name:       "file of the canonical variable",
+			env:        map[string]string{"APP_DB_PASSWORD_FILE": secret},
+			wantValue:  "hunter2",
+			wantSource: SourceSecretFile,
+		},
+		{
+			name:       "file agreeing with the alias",
+			env:        map[string]string{"APP_DB_PASSWORD_FILE": secret, "APP_DB__PASSWORD": "hunter2"},
+			wantValue:  "hunter2",
+			wantSource: SourceSecretFile,
+		},
+		{
+			name:    "file conflicting with the alias",
+			env:     map[string]string{"APP_DB_PASSWORD_FILE": secret, "APP_DB__PASSWORD": "other"},
+			wantErr: "db.password: conflicting values in APP_DB_PASSWORD_FILE and APP_DB__PASSWORD",
+		},
+		{
+			name:    "variable and its file",
+			env:     map[string]string{"APP_DB_PASSWORD": "hunter2", "APP_DB_PASSWORD_FILE": secret},
+			wantErr: "APP_DB_PASSWORD and APP_DB_PASSWORD_FILE are both set, keep only one",
+		},
+		{
+			name:    "missing file",
+			env:     map[string]string{"APP_DB_PASSWORD_FILE": filepath.Join(dir, "missing")},
+			wantErr: "APP_DB_PASSWORD_FILE: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"APP_DB_PASSWORD", "APP_DB_PASSWORD_FILE", "APP_DB__PASSWORD", "APP_DB__PASSWORD_FILE"} {
+				t.Setenv(k, tt.env[k])
+			}
+			r, ok, err := resolveEnv(settingByKey("db.password"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveEnv() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !ok {
+				t.Fatalf("resolveEnv() = %v, %v", ok, err)
+			}
+			if r.value != tt.wantValue || r.source != tt.wantSource || r.secretFile != secret {
+				t.Errorf("resolveEnv() = %+v, want %q from %s", r, tt.wantValue, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestLoadDeprecations(t *testing.T) {
+	t.Setenv("APP_DB__HOST", "legacy-host")
+	t.Setenv("APP_DB_PORT", "6432")
+	t.Setenv("APP_DB__PORT", "6432")
+	snap, err := loadIn(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Config.DatabaseConfig.Host != "legacy-host" || snap.Config.DatabaseConfig.Port != 6432 {
+		t.Errorf("db = %s:%d, want legacy-host:6432", snap.Config.DatabaseConfig.Host, snap.Config.DatabaseConfig.Port)
+	}
+	want := map[string]string{"db.host": "APP_DB__HOST", "db.port": "APP_DB__PORT"}
+	if len(snap.Deprecations) != len(want) {
+		t.Fatalf("Deprecations = %+v, want %v", snap.Deprecations, want)
+	}
+	for _, d := range snap.Deprecations {
+		if want[d.Key] != d.Legacy || d.Canonical != settingByKey(d.Key).Env {
+			t.Errorf("deprecation %+v", d)
+		}
+	}
+
+	t.Setenv("APP_DB_HOST", "other-host")
+	if _, err := loadIn(t, nil); err == nil || !strings.Contains(err.Error(), "conflicting values") {
+		t.Errorf("load() error = %v, want a conflict", err)
+	}
+}
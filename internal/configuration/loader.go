@@ -0,0 +1,259 @@
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Source identifies where an effective configuration value came from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+	// SourceSecretFile is a value read from the file named by <ENV>_FILE.
+	SourceSecretFile Source = "secret-file"
+)
+
+// --config / APP_CONFIG_FILE select an explicit config file instead of the search below.
+const (
+	configFlag    = "config"
+	configFileEnv = "APP_CONFIG_FILE"
+)
+
+// configSearchPaths are scanned (in order) for config.<env>.{yaml,toml,json} then config.{yaml,toml,json}.
+var configSearchPaths = []string{"./config", "."}
+
+// Snapshot is the outcome of a Load: the effective configuration plus
+// the source every key was resolved from.
+type Snapshot struct {
+	Config      *AppConfig
+	Sources     map[string]Source // keyed by setting key, e.g. "rest.port"
+	File        string            // config file used, empty when none was found
+	SecretFiles []string          // files read through <ENV>_FILE variables
+	// Deprecations lists the legacy environment variables the values were read from.
+	Deprecations []Deprecation
+	LoadedAt     time.Time
+}
+
+// Load resolves every setting from, in increasing order of precedence:
+//
+//  1. built-in defaults (see settings.go)
+//  2. the config file: --config / APP_CONFIG_FILE, otherwise the first
+//     config.<APP_ENV>.* then config.* found in ./config or the working directory
+//  3. environment variables, or the file named by <ENV>_FILE (see readSecretFile)
+//  4. command-line flags (args, usually os.Args[1:])
+//
+// On success the result is published through Get and Current.
+func Load(args []string) (*Snapshot, error) {
+	fs := newFlagSet()
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return LoadFlags(fs)
+}
+
+// LoadFlags is Load for a flag set the caller already parsed, so commands can mix
+// their own flags with the configuration ones (see RegisterFlags). fs is kept for Reload.
+func LoadFlags(fs *pflag.FlagSet) (*Snapshot, error) {
+	snap, err := load(fs)
+	if err != nil {
+		return nil, err
+	}
+	loadFlags = fs
+	publish(snap)
+	return snap, nil
+}
+
+func load(fs *pflag.FlagSet) (*Snapshot, error) {
+	file, err := readConfigFile(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := viper.New()
+	sources := make(map[string]Source, len(settings))
+	var (
+		secretFiles  []string
+		deprecations []Deprecation
+		errs         []error
+	)
+	for _, s := range settings {
+		r, err := resolve(s, fs, file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		resolved.Set(s.Key, r.value)
+		sources[s.Key] = r.source
+		if r.secretFile != "" {
+			secretFiles = append(secretFiles, r.secretFile)
+		}
+		if r.deprecated != nil {
+			deprecations = append(deprecations, *r.deprecated)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	cfg := &AppConfig{}
+	if err := resolved.Unmarshal(cfg, viper.DecodeHook(decodeHook)); err != nil {
+		return nil, fmt.Errorf("decode configuration: %w", err)
+	}
+
+	snap := &Snapshot{
+		Config:       cfg,
+		Sources:      sources,
+		SecretFiles:  secretFiles,
+		Deprecations: deprecations,
+		LoadedAt:     time.Now().UTC(),
+	}
+	if file != nil {
+		snap.File = file.ConfigFileUsed()
+	}
+	return snap, nil
+}
+
+// Keys returns the setting keys in a stable order, handy for reporting sources.
+func (s *Snapshot) Keys() []string {
+	keys := make([]string, 0, len(s.Sources))
+	for k := range s.Sources {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resolution is the effective value of one setting.
+type resolution struct {
+	value      any
+	source     Source
+	secretFile string       // set when source is SourceSecretFile
+	deprecated *Deprecation // set when the value came from a legacy environment variable
+}
+
+// resolve walks the layers from highest to lowest precedence and returns the first hit.
+func resolve(s setting, fs *pflag.FlagSet, file *viper.Viper) (resolution, error) {
+	if f := fs.Lookup(s.flagName()); f != nil && f.Changed {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			return resolution{value: sv.GetSlice(), source: SourceFlag}, nil
+		}
+		return resolution{value: f.Value.String(), source: SourceFlag}, nil
+	}
+	if r, ok, err := resolveEnv(s); err != nil || ok {
+		return r, err
+	}
+	if file != nil && file.InConfig(s.Key) {
+		return resolution{value: file.Get(s.Key), source: SourceFile}, nil
+	}
+	return resolution{value: s.Default, source: SourceDefault}, nil
+}
+
+// decodeHook extends viper's default hooks so that maps and lists (feature flags, ...)
+// can also be given as JSON in a single environment variable or flag.
+var decodeHook = mapstructure.ComposeDecodeHookFunc(
+	jsonStringHook,
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+)
+
+func jsonStringHook(f reflect.Type, t reflect.Type, data any) (any, error) {
+	if f.Kind() != reflect.String {
+		return data, nil
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice:
+	default:
+		return data, nil
+	}
+	raw := strings.TrimSpace(reflect.ValueOf(data).String())
+	if raw == "" || (raw[0] != '{' && raw[0] != '[') {
+		return data, nil
+	}
+	var out any
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		return nil, fmt.Errorf("invalid JSON value: %w", err)
+	}
+	return out, nil
+}
+
+func newFlagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet(filepath.Base(os.Args[0]), pflag.ContinueOnError)
+	fs.SortFlags = false
+	RegisterFlags(fs)
+	return fs
+}
+
+// RegisterFlags adds --config and one flag per setting, typed after its default.
+func RegisterFlags(fs *pflag.FlagSet) {
+	fs.String(configFlag, "", "path to a YAML/TOML/JSON config file (env: "+configFileEnv+")")
+
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (env: %s)", s.Usage, s.Env)
+		switch def := s.Default.(type) {
+		case int:
+			fs.Int(s.flagName(), def, usage)
+		case bool:
+			fs.Bool(s.flagName(), def, usage)
+		case time.Duration:
+			fs.Duration(s.flagName(), def, usage)
+		case []string:
+			fs.StringSlice(s.flagName(), def, usage)
+		case map[string]any:
+			fs.String(s.flagName(), "", usage+" (JSON)")
+		default:
+			fs.String(s.flagName(), fmt.Sprint(def), usage)
+		}
+	}
+}
+
+// readConfigFile returns a viper instance holding the selected config file, or nil when none exists.
+func readConfigFile(fs *pflag.FlagSet) (*viper.Viper, error) {
+	path, _ := fs.GetString(configFlag)
+	if path == "" {
+		path = os.Getenv(configFileEnv)
+	}
+	if path != "" {
+		v := viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("read config file %q: %w", path, err)
+		}
+		return v, nil
+	}
+
+	names := []string{"config"}
+	if env, _ := resolve(settingByKey("env"), fs, nil); fmt.Sprint(env.value) != "" {
+		names = append([]string{"config." + strings.ToLower(fmt.Sprint(env.value))}, names...)
+	}
+	for _, name := range names {
+		v := viper.New()
+		v.SetConfigName(name)
+		for _, p := range configSearchPaths {
+			v.AddConfigPath(p)
+		}
+		err := v.ReadInConfig()
+		if err == nil {
+			return v, nil
+		}
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("read config file %q: %w", name, err)
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,266 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadIn loads the configuration from dir as the working directory, with files written there first.
+func loadIn(t *testing.T, files map[string]string, args ...string) (*Snapshot, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+	fs := newFlagSet()
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return load(fs)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string // config.yaml
+		env        string // APP_REST_PORT
+		flag       string // --rest-port
+		wantPort   int
+		wantSource Source
+	}{
+		{name: "default", wantPort: 8080, wantSource: SourceDefault},
+		{name: "file over default", file: "rest:\n  port: 9001\n", wantPort: 9001, wantSource: SourceFile},
+		{name: "env over file", file: "rest:\n  port: 9001\n", env: "9002", wantPort: 9002, wantSource: SourceEnv},
+		{name: "flag over env", file: "rest:\n  port: 9001\n", env: "9002", flag: "9003", wantPort: 9003, wantSource: SourceFlag},
+		{name: "flag over file", file: "rest:\n  port: 9001\n", flag: "9003", wantPort: 9003, wantSource: SourceFlag},
+		{name: "flag set to the default", env: "9002", flag: "8080", wantPort: 8080, wantSource: SourceFlag},
+		{name: "file without the key", file: "rest:\n  host: 127.0.0.1\n", wantPort: 8080, wantSource: SourceDefault},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{}
+			if tt.file != "" {
+				files["config.yaml"] = tt.file
+			}
+			t.Setenv("APP_REST_PORT", tt.env)
+			var args []string
+			if tt.flag != "" {
+				args = append(args, "--rest-port", tt.flag)
+			}
+			snap, err := loadIn(t, files, args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := snap.Config.RestConfig.Port; got != tt.wantPort || snap.Sources["rest.port"] != tt.wantSource {
+				t.Errorf("rest.port = %d from %s, want %d from %s", got, snap.Sources["rest.port"], tt.wantPort, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string // written in the working directory
+		env      map[string]string
+		args     []string
+		wantName string // app name, set differently by every file
+		wantFile string // suffix of Snapshot.File
+		wantErr  string
+	}{
+		{name: "no file", wantName: "archetype"},
+		{
+			name:     "config.yaml",
+			files:    map[string]string{"config.yaml": "name: base\n"},
+			wantName: "base",
+			wantFile: "config.yaml",
+		},
+		{
+			name:     "config/ before the working directory",
+			files:    map[string]string{"config.yaml": "name: base\n", "config/config.yaml": "name: nested\n"},
+			wantName: "nested",
+			wantFile: filepath.Join("config", "config.yaml"),
+		},
+		{
+			name:     "config.<env> before config",
+			files:    map[string]string{"config.yaml": "name: base\n", "config.dev.yaml": "name: dev\n"},
+			wantName: "dev",
+			wantFile: "config.dev.yaml",
+		},
+		{
+			name:     "env from APP_ENV",
+			files:    map[string]string{"config.dev.yaml": "name: dev\n", "config.prod.json": `{"name": "prod"}`},
+			env:      map[string]string{"APP_ENV": "PROD"},
+			wantName: "prod",
+			wantFile: "config.prod.json",
+		},
+		{
+			name:     "env from --env",
+			files:    map[string]string{"config.dev.yaml": "name: dev\n", "config.staging.toml": "name = \"staging\"\n"},
+			env:      map[string]string{"APP_ENV": "prod"},
+			args:     []string{"--env", "staging"},
+			wantName: "staging",
+			wantFile: "config.staging.toml",
+		},
+		{
+			name:     "no config.<env>, falls back to config",
+			files:    map[string]string{"config.yaml": "name: base\n", "config.dev.yaml": "name: dev\n"},
+			env:      map[string]string{"APP_ENV": "prod"},
+			wantName: "base",
+			wantFile: "config.yaml",
+		},
+		{
+			name:     "APP_CONFIG_FILE",
+			files:    map[string]string{"config.yaml": "name: base\n", "custom.yaml": "name: custom\n"},
+			env:      map[string]string{"APP_CONFIG_FILE": "custom.yaml"},
+			wantName: "custom",
+			wantFile: "custom.yaml",
+		},
+		{
+			name:     "--config over APP_CONFIG_FILE",
+			files:    map[string]string{"custom.yaml": "name: custom\n", "other.yaml": "name: other\n"},
+			env:      map[string]string{"APP_CONFIG_FILE": "custom.yaml"},
+			args:     []string{"--config", "other.yaml"},
+			wantName: "other",
+			wantFile: "other.yaml",
+		},
+		{
+			name:    "missing explicit file",
+			env:     map[string]string{"APP_CONFIG_FILE": "missing.yaml"},
+			wantErr: `read config file "missing.yaml"`,
+		},
+		{
+			name:    "malformed file",
+			files:   map[string]string{"config.yaml": "name: [\n"},
+			wantErr: `read config file "config"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"APP_ENV", "APP_CONFIG_FILE", "APP_NAME"} {
+				t.Setenv(k, tt.env[k])
+			}
+			snap, err := loadIn(t, tt.files, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("load() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if snap.Config.AppName != tt.wantName {
+				t.Errorf("name = %q, want %q", snap.Config.AppName, tt.wantName)
+			}
+			if (tt.wantFile == "") != (snap.File == "") || !strings.HasSuffix(snap.File, tt.wantFile) {
+				t.Errorf("File = %q, want %q", snap.File, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestLoadDecoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		file    string
+		check   func(t *testing.T, cfg *AppConfig)
+		wantErr string
+	}{
+		{
+			name: "JSON map from the environment",
+			env:  map[string]string{"APP_FEATURES_FLAGS": ` {"beta": {"enabled": true, "percentage": 10, "tenants": ["acme"]}}`},
+			check: func(t *testing.T, cfg *AppConfig) {
+				beta, ok := cfg.FeaturesConfig.Flags["beta"]
+				if !ok || !beta.Enabled || beta.Percentage == nil || *beta.Percentage != 10 || !slices.Equal(beta.Tenants, []string{"acme"}) {
+					t.Errorf("flags = %+v", cfg.FeaturesConfig.Flags)
+				}
+			},
+		},
+		{
+			name: "JSON map from a flag",
+			args: []string{`--features-flags={"beta": {"enabled": false}}`},
+			check: func(t *testing.T, cfg *AppConfig) {
+				if beta, ok := cfg.FeaturesConfig.Flags["beta"]; !ok || beta.Enabled {
+					t.Errorf("flags = %+v", cfg.FeaturesConfig.Flags)
+				}
+			},
+		},
+		{
+			name: "map from the file",
+			file: "features:\n  flags:\n    beta:\n      enabled: true\n",
+			check: func(t *testing.T, cfg *AppConfig) {
+				if beta, ok := cfg.FeaturesConfig.Flags["beta"]; !ok || !beta.Enabled {
+					t.Errorf("flags = %+v", cfg.FeaturesConfig.Flags)
+				}
+			},
+		},
+		{
+			name:    "invalid JSON",
+			env:     map[string]string{"APP_FEATURES_FLAGS": `{"beta": `},
+			wantErr: "invalid JSON value",
+		},
+		{
+			name: "comma-separated list from the environment",
+			env:  map[string]string{"APP_CHECK_SERVICE_URLS": "https://a.example,https://b.example"},
+			check: func(t *testing.T, cfg *AppConfig) {
+				if got := cfg.ChecksConfig.ServiceURLs; !slices.Equal(got, []string{"https://a.example", "https://b.example"}) {
+					t.Errorf("service_urls = %v", got)
+				}
+			},
+		},
+		{
+			name: "JSON list from the environment",
+			env:  map[string]string{"APP_CHECK_SERVICE_URLS": `["https://a.example"]`},
+			check: func(t *testing.T, cfg *AppConfig) {
+				if got := cfg.ChecksConfig.ServiceURLs; !slices.Equal(got, []string{"https://a.example"}) {
+					t.Errorf("service_urls = %v", got)
+				}
+			},
+		},
+		{
+			name: "duration",
+			env:  map[string]string{"APP_REST_TLS_RELOAD_INTERVAL": "1m30s"},
+			check: func(t *testing.T, cfg *AppConfig) {
+				if got := cfg.RestConfig.TLS.ReloadInterval; got != 90*time.Second {
+					t.Errorf("reload_interval = %s", got)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"APP_FEATURES_FLAGS", "APP_CHECK_SERVICE_URLS", "APP_REST_TLS_RELOAD_INTERVAL"} {
+				t.Setenv(k, tt.env[k])
+			}
+			files := map[string]string{}
+			if tt.file != "" {
+				files["config.yaml"] = tt.file
+			}
+			snap, err := loadIn(t, files, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("load() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, snap.Config)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package configuration
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Mask replaces secret values in every redacted output.
+const Mask = "******"
+
+// keywordPassword matches password=... in keyword/value connection strings.
+var keywordPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Description is the redacted, introspectable view of the configuration in effect.
+type Description struct {
+	Config       map[string]any    `json:"config"`  // keyed like the config file
+	Sources      map[string]Source `json:"sources"` // keyed by setting key
+	File         string            `json:"file,omitempty"`
+	Deprecations []Deprecation     `json:"deprecations,omitempty"`
+	LoadedAt     time.Time         `json:"loadedAt"`
+	Reload       ReloadStatus      `json:"reload"`
+}
+
+// Describe returns the current configuration with secrets masked, where each value came from
+// and the last reload status. It returns nil before Load.
+func Describe() *Description {
+	snap := Current()
+	if snap == nil {
+		return nil
+	}
+	cfg, _ := snap.Config.Redacted().(map[string]any)
+	return &Description{
+		Config:       cfg,
+		Sources:      snap.Sources,
+		File:         snap.File,
+		Deprecations: snap.Deprecations,
+		LoadedAt:     snap.LoadedAt,
+		Reload:       LastReload(),
+	}
+}
+
+// Redacted converts the configuration into nested maps keyed by mapstructure tags.
+// Fields tagged `secret:"true"` are masked, fields tagged `secret:"dsn"` keep
+// everything but their credentials.
+func (c *AppConfig) Redacted() any {
+	return redact(reflect.ValueOf(c))
+}
+
+func redact(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redact(v.Elem())
+	case reflect.Struct:
+		out := map[string]any{}
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			key := f.Tag.Get("mapstructure")
+			if key == "" {
+				key = f.Name
+			}
+			switch f.Tag.Get("secret") {
+			case "true":
+				out[key] = maskValue(v.Field(i))
+			case "dsn":
+				out[key] = RedactDSN(v.Field(i).String())
+			default:
+				out[key] = redact(v.Field(i))
+			}
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			out[fmt.Sprint(it.Key().Interface())] = redact(it.Value())
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = redact(v.Index(i))
+		}
+		return out
+	default:
+		if d, ok := v.Interface().(time.Duration); ok {
+			return d.String() // as written in config files, not nanoseconds
+		}
+		return v.Interface()
+	}
+}
+
+// maskValue hides a secret but still tells whether it is set.
+func maskValue(v reflect.Value) string {
+	if v.IsZero() {
+		return ""
+	}
+	return Mask
+}
+
+// RedactDSN masks the password of a URL or keyword/value PostgreSQL connection string.
+func RedactDSN(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return Mask
+		}
+		// url.URL escapes '*', so mask with a placeholder and swap it afterwards
+		const placeholder = "REDACTED"
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), placeholder)
+		}
+		if q := u.Query(); q.Has("password") {
+			q.Set("password", placeholder)
+			u.RawQuery = q.Encode()
+		}
+		return strings.ReplaceAll(u.String(), placeholder, Mask)
+	}
+	return keywordPassword.ReplaceAllString(dsn, "${1}"+Mask)
+}
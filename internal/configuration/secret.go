@@ -0,0 +1,88 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// fileSuffix turns any environment variable into a pointer to a file holding its value,
+// e.g. APP_DB_PASSWORD_FILE=/run/secrets/db-password.
+const fileSuffix = "_FILE"
+
+// maxSecretFileSize guards against pointing a _FILE variable at something that is not a secret.
+const maxSecretFileSize = 64 << 10
+
+// Secret holds a sensitive value. It prints as Mask through fmt, slog and JSON;
+// call Reveal to get the actual value.
+type Secret string
+
+// Reveal returns the secret value.
+func (s Secret) Reveal() string { return string(s) }
+
+func (s Secret) String() string { return s.masked() }
+
+// Format covers every fmt verb (%s, %v, %#v, %q, %x, ...), not only those using String.
+func (s Secret) Format(f fmt.State, verb rune) { _, _ = f.Write([]byte(s.masked())) }
+
+func (s Secret) LogValue() slog.Value { return slog.StringValue(s.masked()) }
+
+func (s Secret) MarshalJSON() ([]byte, error) { return []byte(`"` + s.masked() + `"`), nil }
+
+func (s Secret) MarshalText() ([]byte, error) { return []byte(s.masked()), nil }
+
+// masked keeps telling apart an unset secret from a set one.
+func (s Secret) masked() string {
+	if s == "" {
+		return ""
+	}
+	return Mask
+}
+
+// DSN holds a connection string, which may carry a password. It prints through fmt, slog and
+// JSON with the password masked (see RedactDSN); call Reveal to connect with it.
+type DSN string
+
+// Reveal returns the connection string, password included.
+func (d DSN) Reveal() string { return string(d) }
+
+func (d DSN) String() string { return RedactDSN(string(d)) }
+
+// Format covers every fmt verb, like Secret.Format.
+func (d DSN) Format(f fmt.State, verb rune) { _, _ = f.Write([]byte(d.String())) }
+
+func (d DSN) LogValue() slog.Value { return slog.StringValue(d.String()) }
+
+func (d DSN) MarshalJSON() ([]byte, error) { return json.Marshal(d.String()) }
+
+func (d DSN) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+// readSecretFile reads the value of a <KEY>_FILE variable. The file must be a regular
+// file no larger than maxSecretFileSize that is not writable by group or others;
+// surrounding whitespace (the trailing newline of most secret files) is trimmed.
+func readSecretFile(env, path string) (string, error) {
+	fi, err := os.Stat(path) // follows the symlinks Kubernetes uses for secret volumes
+	if err != nil {
+		return "", fmt.Errorf("%s%s: %w", env, fileSuffix, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return "", fmt.Errorf("%s%s: %q is not a regular file", env, fileSuffix, path)
+	}
+	if fi.Mode().Perm()&0o022 != 0 {
+		return "", fmt.Errorf("%s%s: %q is writable by group or others (mode %s)", env, fileSuffix, path, fi.Mode().Perm())
+	}
+	if fi.Size() > maxSecretFileSize {
+		return "", fmt.Errorf("%s%s: %q is larger than %d bytes", env, fileSuffix, path, maxSecretFileSize)
+	}
+	if fi.Mode().Perm()&0o004 != 0 {
+		slog.Warn("secret file is world-readable", "env", env+fileSuffix, "path", path, "mode", fi.Mode().Perm().String())
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s%s: %w", env, fileSuffix, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,124 @@
+package configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactDSN(t *testing.T) {
+	tests := []struct {
+		name, dsn, want string
+	}{
+		{"empty", "", ""},
+		{"url", "postgres://app:hunter2@db:5432/app?sslmode=require", "postgres://app:******@db:5432/app?sslmode=require"},
+		{"postgresql scheme", "postgresql://app:hunter2@db/app", "postgresql://app:******@db/app"},
+		{"url without password", "postgres://app@db/app", "postgres://app@db/app"},
+		{"url escaped password", "postgres://app:p%40ss%2Fw@db/app", "postgres://app:******@db/app"},
+		{"password in the query", "postgres://app@db/app?password=hunter2&sslmode=disable", "postgres://app@db/app?password=******&sslmode=disable"},
+		{"unparsable url", "postgres://app:hunter2@db:port/app", Mask},
+		{"keyword", "host=db user=app password=hunter2 dbname=app", "host=db user=app password=****** dbname=app"},
+		{"keyword quoted", "host=db password='hunter 2' dbname=app", "host=db password=****** dbname=app"},
+		{"keyword quoted escape", `password='it\'s' host=db`, "password=****** host=db"},
+		{"keyword spaces and case", "host=db PASSWORD = hunter2", "host=db PASSWORD = ******"},
+		{"keyword without password", "host=db user=app", "host=db user=app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactDSN(tt.dsn); got != tt.want {
+				t.Errorf("RedactDSN(%q) = %q, want %q", tt.dsn, got, tt.want)
+			}
+		})
+	}
+}
+
+// renderings prints v through every way a value leaks into logs and responses.
+func renderings(t *testing.T, v any) map[string]string {
+	t.Helper()
+	j, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	var logs bytes.Buffer
+	slog.New(slog.NewTextHandler(&logs, nil)).Info("msg", "value", v)
+	return map[string]string{
+		"%s":   fmt.Sprintf("%s", v),
+		"%v":   fmt.Sprintf("%v", v),
+		"%+v":  fmt.Sprintf("%+v", v),
+		"%#v":  fmt.Sprintf("%#v", v),
+		"%q":   fmt.Sprintf("%q", v),
+		"%x":   fmt.Sprintf("%x", v),
+		"json": string(j),
+		"slog": logs.String(),
+	}
+}
+
+func TestSecretMasking(t *testing.T) {
+	const password = "hunter2"
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"secret", Secret(password)},
+		{"dsn url", DSN("postgres://app:" + password + "@db/app")},
+		{"dsn keyword", DSN("host=db password=" + password)},
+		{"database config", DatabaseConfig{
+			DSN:      DSN("postgres://app:" + password + "@db/app"),
+			Password: Secret(password),
+			Targets:  map[string]DatabaseTargetConfig{"replica": {DSN: DSN("host=replica password=" + password)}},
+		}},
+		{"pointer", &DatabaseConfig{Password: Secret(password)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for how, out := range renderings(t, tt.value) {
+				if strings.Contains(out, password) || strings.Contains(out, fmt.Sprintf("%x", password)) {
+					t.Errorf("%s leaks the password: %s", how, out)
+				}
+			}
+		})
+	}
+}
+
+func TestSecretReveal(t *testing.T) {
+	if got := Secret("hunter2").Reveal(); got != "hunter2" {
+		t.Errorf("Secret.Reveal() = %q", got)
+	}
+	if got := Secret("").String(); got != "" {
+		t.Errorf("empty Secret prints %q, want it empty", got)
+	}
+	if got := Secret("x").String(); got != Mask {
+		t.Errorf("Secret prints %q, want %q", got, Mask)
+	}
+	dsn := DSN("postgres://app:hunter2@db/app")
+	if got := dsn.Reveal(); got != "postgres://app:hunter2@db/app" {
+		t.Errorf("DSN.Reveal() = %q", got)
+	}
+	if got := dsn.String(); got != "postgres://app:******@db/app" {
+		t.Errorf("DSN prints %q", got)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := &AppConfig{DatabaseConfig: &DatabaseConfig{
+		DSN:      DSN("postgres://app:hunter2@db/app"),
+		Password: Secret("hunter2"),
+		Targets:  map[string]DatabaseTargetConfig{"replica": {DSN: DSN("host=replica password=hunter2")}},
+	}}
+	out, err := json.Marshal(cfg.Redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(out)
+	if strings.Contains(s, "hunter2") {
+		t.Errorf("Redacted() leaks the password: %s", s)
+	}
+	for _, want := range []string{`"dsn":"postgres://app:******@db/app"`, `"password":"******"`, `"dsn":"host=replica password=******"`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Redacted() = %s, want it to contain %s", s, want)
+		}
+	}
+}
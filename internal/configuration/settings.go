@@ -0,0 +1,125 @@
+package configuration
+
+import (
+	"strings"
+	"time"
+)
+
+// setting declares one configuration key, its environment variable and its default.
+// The command-line flag is derived from Key ("rest.port" -> --rest-port).
+type setting struct {
+	Key     string   // dotted key used in config files, e.g. "rest.port"
+	Env     string   // canonical environment variable
+	Aliases []string // legacy environment variables, still read but logged as deprecated
+	Default any      // also decides the flag type (string, int, bool, time.Duration, []string, map[string]any)
+	Usage   string
+}
+
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.Key)
+}
+
+// settings is the single list of everything AppConfig can be configured with.
+// Keep it in sync with the mapstructure tags in configuration.go.
+var settings = []setting{
+	// Application
+	{Key: "name", Env: "APP_NAME", Default: "archetype", Usage: "application name"},
+	{Key: "version", Env: "APP_VERSION", Default: "unknown", Usage: "application version"},
+	{Key: "revision", Env: "APP_REVISION", Default: "unknown", Usage: "VCS revision the binary was built from"},
+	{Key: "built_at", Env: "APP_BUILT_AT", Default: "unknown", Usage: "build timestamp (RFC 3339)"},
+	{Key: "data_dir", Env: "APP_DATA_DIR", Default: "./data", Usage: "directory served by the data endpoints"},
+	{Key: "env", Env: "APP_ENV", Default: "dev", Usage: "environment name, also selects config.<env>.* files"},
+
+	// Logging
+	{Key: "log.level", Env: "APP_LOG_LEVEL", Default: "info", Usage: "log level: debug | info | warn | error"},
+
+	// REST
+	{Key: "rest.host", Env: "APP_REST_HOST", Default: "0.0.0.0", Usage: "REST listen host"},
+	{Key: "rest.port", Env: "APP_REST_PORT", Default: 8080, Usage: "REST listen port"},
+	{Key: "rest.admin_host", Env: "APP_REST_ADMIN_HOST", Default: "0.0.0.0", Usage: "admin (technical routes, monitoring UI) listen host"},
+	{Key: "rest.admin_port", Env: "APP_REST_ADMIN_PORT", Default: 8081, Usage: "admin (technical routes, monitoring UI) listen port"},
+	{Key: "rest.single_port", Env: "APP_REST_SINGLE_PORT", Default: false, Usage: "serve technical routes on the REST port too, without an admin listener (local development)"},
+	{Key: "rest.tcp", Env: "APP_REST_TCP", Default: true, Usage: "listen on rest.host:rest.port (disable to serve only on a unix socket or systemd sockets)"},
+	{Key: "rest.unix_socket", Env: "APP_REST_UNIX_SOCKET", Default: "", Usage: "also serve the public routes on this unix socket path"},
+	{Key: "rest.unix_socket_mode", Env: "APP_REST_UNIX_SOCKET_MODE", Default: "0660", Usage: "file mode of the unix socket (octal)"},
+	{Key: "rest.unix_socket_owner", Env: "APP_REST_UNIX_SOCKET_OWNER", Default: "", Usage: "owner of the unix socket: user:group or uid:gid"},
+	{Key: "rest.read_timeout", Env: "APP_REST_READ_TIMEOUT", Default: 15 * time.Second, Usage: "max duration to read a whole request"},
+	{Key: "rest.read_header_timeout", Env: "APP_REST_READ_HEADER_TIMEOUT", Default: 5 * time.Second, Usage: "max duration to read request headers"},
+	{Key: "rest.write_timeout", Env: "APP_REST_WRITE_TIMEOUT", Default: 30 * time.Second, Usage: "max duration to write a response"},
+	{Key: "rest.idle_timeout", Env: "APP_REST_IDLE_TIMEOUT", Default: 60 * time.Second, Usage: "keep-alive idle timeout"},
+	{Key: "rest.pre_stop_delay", Env: "APP_REST_PRE_STOP_DELAY", Default: 5 * time.Second, Usage: "delay between failing readiness and draining on shutdown"},
+	{Key: "rest.shutdown_timeout", Env: "APP_REST_SHUTDOWN_TIMEOUT", Default: 20 * time.Second, Usage: "deadline to drain connections on shutdown"},
+	{Key: "rest.tls.cert_file", Env: "APP_REST_TLS_CERT_FILE", Default: "", Usage: "PEM certificate (chain) served over HTTPS; enables TLS with rest.tls.key_file"},
+	{Key: "rest.tls.key_file", Env: "APP_REST_TLS_KEY_FILE", Default: "", Usage: "PEM private key of rest.tls.cert_file"},
+	{Key: "rest.tls.client_ca_file", Env: "APP_REST_TLS_CLIENT_CA_FILE", Default: "", Usage: "PEM CA bundle verifying client certificates; enables mutual TLS"},
+	{Key: "rest.tls.client_auth", Env: "APP_REST_TLS_CLIENT_AUTH", Default: "require", Usage: "with a client CA: require | verify-if-given"},
+	{Key: "rest.tls.reload_interval", Env: "APP_REST_TLS_RELOAD_INTERVAL", Default: 30 * time.Second, Usage: "how often certificate files are checked for rotation"},
+
+	// Liveness
+	{Key: "liveness.max_goroutines", Env: "APP_LIVENESS_MAX_GOROUTINES", Default: 10000, Usage: "fail liveness above this many goroutines (0 disables)"},
+	{Key: "liveness.max_memory_mb", Env: "APP_LIVENESS_MAX_MEMORY_MB", Default: 0, Usage: "fail liveness above this much runtime memory in MiB (0 disables)"},
+
+	// Database
+	{Key: "db.dsn", Env: "APP_DB_DSN", Aliases: []string{"APP_DB__DSN"}, Default: "", Usage: "full PostgreSQL DSN"},
+	{Key: "db.name", Env: "APP_DB_NAME", Aliases: []string{"APP_DB__NAME"}, Default: "", Usage: "database name"},
+	{Key: "db.host", Env: "APP_DB_HOST", Aliases: []string{"APP_DB__HOST"}, Default: "", Usage: "database host"},
+	{Key: "db.port", Env: "APP_DB_PORT", Aliases: []string{"APP_DB__PORT"}, Default: 5432, Usage: "database port"},
+	{Key: "db.username", Env: "APP_DB_USER", Aliases: []string{"APP_DB__USERNAME"}, Default: "", Usage: "database user"},
+	{Key: "db.password", Env: "APP_DB_PASSWORD", Aliases: []string{"APP_DB__PASSWORD"}, Default: "", Usage: "database password"},
+	{Key: "db.ssl", Env: "APP_DB_SSLMODE", Aliases: []string{"APP_DB_SSL"}, Default: "require", Usage: "sslmode: disable | require | verify-ca | verify-full"},
+	{Key: "db.addr", Env: "APP_DB_ADDR", Default: "", Usage: "host:port probed over TCP when no DSN can be built"},
+	{Key: "db.role", Env: "APP_DB_ROLE", Default: "", Usage: "expected role of this database, verified by the database check: primary | replica (empty: not verified)"},
+	{Key: "db.tls.ca_file", Env: "APP_DB_TLS_CA_FILE", Default: "", Usage: "PEM CA bundle verifying the database server certificate (sslrootcert)"},
+	{Key: "db.tls.cert_file", Env: "APP_DB_TLS_CERT_FILE", Default: "", Usage: "PEM client certificate presented to the database (sslcert)"},
+	{Key: "db.tls.key_file", Env: "APP_DB_TLS_KEY_FILE", Default: "", Usage: "PEM private key of db.tls.cert_file (sslkey)"},
+	{Key: "db.tls.server_name", Env: "APP_DB_TLS_SERVER_NAME", Default: "", Usage: "server name verified with sslmode verify-full and sent as SNI, instead of the host"},
+	{Key: "db.tls.min_version", Env: "APP_DB_TLS_MIN_VERSION", Default: "1.2", Usage: "minimum TLS version of database connections: 1.2 | 1.3"},
+	{Key: "db.targets", Env: "APP_DB_TARGETS", Default: map[string]any{}, Usage: "more databases probed by the database check, keyed by name: dsn, role, critical"},
+	{Key: "db.statement_timeout", Env: "APP_DB_STATEMENT_TIMEOUT", Default: time.Duration(0), Usage: "statement_timeout of pooled connections (0 keeps the server's)"},
+	{Key: "db.pool.max_conns", Env: "APP_DB_POOL_MAX_CONNS", Default: 10, Usage: "maximum size of the connection pool"},
+	{Key: "db.pool.min_conns", Env: "APP_DB_POOL_MIN_CONNS", Default: 0, Usage: "connections the pool keeps open even when idle"},
+	{Key: "db.pool.max_conn_lifetime", Env: "APP_DB_POOL_MAX_CONN_LIFETIME", Default: time.Hour, Usage: "close pooled connections older than this"},
+	{Key: "db.pool.max_conn_idle_time", Env: "APP_DB_POOL_MAX_CONN_IDLE_TIME", Default: 30 * time.Minute, Usage: "close pooled connections idle for longer than this"},
+	{Key: "db.pool.health_check_period", Env: "APP_DB_POOL_HEALTH_CHECK_PERIOD", Default: time.Minute, Usage: "how often idle pooled connections are checked"},
+	{Key: "db.trace.enabled", Env: "APP_DB_TRACE_ENABLED", Default: true, Usage: "trace the queries of the shared pool: latency histograms and slow query log"},
+	{Key: "db.trace.slow_threshold", Env: "APP_DB_TRACE_SLOW_THRESHOLD", Default: 200 * time.Millisecond, Usage: "log and keep queries slower than this (0 disables)"},
+	{Key: "db.trace.slow_queries", Env: "APP_DB_TRACE_SLOW_QUERIES", Default: 50, Usage: "number of recent slow queries kept for /api/check/database/slow-queries"},
+	{Key: "db.notify.channels", Env: "APP_DB_NOTIFY_CHANNELS", Default: []string{}, Usage: "comma-separated PostgreSQL channels to LISTEN on and stream at /api/events/:channel"},
+	{Key: "db.notify.client_buffer", Env: "APP_DB_NOTIFY_CLIENT_BUFFER", Default: 64, Usage: "notifications queued per event stream client before it is dropped as too slow"},
+	{Key: "db.notify.max_reconnect_delay", Env: "APP_DB_NOTIFY_MAX_RECONNECT_DELAY", Default: 30 * time.Second, Usage: "ceiling of the LISTEN connection's reconnection backoff"},
+	{Key: "db.migrations.auto", Env: "APP_DB_MIGRATIONS_AUTO", Default: false, Usage: "apply pending migrations at startup (otherwise readiness waits for the migrate command)"},
+	{Key: "db.migrations.table", Env: "APP_DB_MIGRATIONS_TABLE", Default: "schema_migrations", Usage: "table recording the applied migrations"},
+	{Key: "db.migrations.timeout", Env: "APP_DB_MIGRATIONS_TIMEOUT", Default: 10 * time.Minute, Usage: "deadline of one startup migration attempt, lock wait included"},
+	{Key: "db.diagnostics.connections_warning_pct", Env: "APP_DB_DIAGNOSTICS_CONNECTIONS_WARNING_PCT", Default: 80, Usage: "deep database check: degraded from this share of max_connections in use"},
+	{Key: "db.diagnostics.connections_critical_pct", Env: "APP_DB_DIAGNOSTICS_CONNECTIONS_CRITICAL_PCT", Default: 95, Usage: "deep database check: failed from this share of max_connections in use"},
+	{Key: "db.diagnostics.replica_lag_warning", Env: "APP_DB_DIAGNOSTICS_REPLICA_LAG_WARNING", Default: 30 * time.Second, Usage: "deep database check: degraded from this replay lag on a replica"},
+	{Key: "db.diagnostics.replica_lag_critical", Env: "APP_DB_DIAGNOSTICS_REPLICA_LAG_CRITICAL", Default: 5 * time.Minute, Usage: "deep database check: failed from this replay lag on a replica"},
+	{Key: "db.diagnostics.size_warning_mb", Env: "APP_DB_DIAGNOSTICS_SIZE_WARNING_MB", Default: 0, Usage: "deep database check: degraded from this database size in MiB"},
+	{Key: "db.diagnostics.size_critical_mb", Env: "APP_DB_DIAGNOSTICS_SIZE_CRITICAL_MB", Default: 0, Usage: "deep database check: failed from this database size in MiB"},
+	{Key: "db.diagnostics.long_query_warning", Env: "APP_DB_DIAGNOSTICS_LONG_QUERY_WARNING", Default: 5 * time.Minute, Usage: "deep database check: degraded when a statement runs this long"},
+	{Key: "db.diagnostics.long_query_critical", Env: "APP_DB_DIAGNOSTICS_LONG_QUERY_CRITICAL", Default: 30 * time.Minute, Usage: "deep database check: failed when a statement runs this long"},
+	{Key: "db.diagnostics.idle_in_tx_warning", Env: "APP_DB_DIAGNOSTICS_IDLE_IN_TX_WARNING", Default: time.Minute, Usage: "deep database check: degraded when a session is idle in transaction this long"},
+	{Key: "db.diagnostics.idle_in_tx_critical", Env: "APP_DB_DIAGNOSTICS_IDLE_IN_TX_CRITICAL", Default: 10 * time.Minute, Usage: "deep database check: failed when a session is idle in transaction this long"},
+	{Key: "db.diagnostics.lock_wait_warning", Env: "APP_DB_DIAGNOSTICS_LOCK_WAIT_WARNING", Default: 30 * time.Second, Usage: "deep database check: degraded when a session waits this long on a lock"},
+	{Key: "db.diagnostics.lock_wait_critical", Env: "APP_DB_DIAGNOSTICS_LOCK_WAIT_CRITICAL", Default: 5 * time.Minute, Usage: "deep database check: failed when a session waits this long on a lock"},
+	{Key: "db.diagnostics.cache_hit_warning_pct", Env: "APP_DB_DIAGNOSTICS_CACHE_HIT_WARNING_PCT", Default: 95, Usage: "deep database check: degraded below this buffer cache hit ratio"},
+	{Key: "db.diagnostics.cache_hit_critical_pct", Env: "APP_DB_DIAGNOSTICS_CACHE_HIT_CRITICAL_PCT", Default: 0, Usage: "deep database check: failed below this buffer cache hit ratio"},
+	{Key: "db.diagnostics.wraparound_warning_pct", Env: "APP_DB_DIAGNOSTICS_WRAPAROUND_WARNING_PCT", Default: 50, Usage: "deep database check: degraded from this share of transaction IDs used before wraparound"},
+	{Key: "db.diagnostics.wraparound_critical_pct", Env: "APP_DB_DIAGNOSTICS_WRAPAROUND_CRITICAL_PCT", Default: 75, Usage: "deep database check: failed from this share of transaction IDs used before wraparound"},
+
+	// Checks
+	{Key: "checks.service_urls", Env: "APP_CHECK_SERVICE_URLS", Default: []string{"https://api.github.com"}, Usage: "comma-separated URLs probed by /api/check/services"},
+
+	// Feature flags
+	{Key: "features.tenant_header", Env: "APP_FEATURES_TENANT_HEADER", Default: "X-Tenant-ID", Usage: "request header carrying the tenant used by flag targeting"},
+	{Key: "features.flags", Env: "APP_FEATURES_FLAGS", Default: map[string]any{}, Usage: "feature flag definitions keyed by name"},
+}
+
+func settingByKey(key string) setting {
+	for _, s := range settings {
+		if s.Key == key {
+			return s
+		}
+	}
+	return setting{Key: key}
+}
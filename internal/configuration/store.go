@@ -0,0 +1,133 @@
+package configuration
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// The published snapshot is swapped atomically so readers never see a half-applied reload.
+var (
+	store     atomic.Pointer[Snapshot]
+	loadFlags *pflag.FlagSet
+
+	reloadMu sync.Mutex
+	status   atomic.Pointer[ReloadStatus]
+
+	subMu       sync.Mutex
+	subID       int
+	subscribers = map[int]Subscriber{}
+)
+
+// Subscriber is called after a new configuration has been published.
+// old is never nil; callbacks run synchronously and should return quickly.
+type Subscriber func(old, new *AppConfig)
+
+// ReloadStatus describes the outcome of the last (re)load attempt.
+type ReloadStatus struct {
+	File        string    `json:"file,omitempty"`
+	LastAttempt time.Time `json:"lastAttempt"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	Reloads     int       `json:"reloads"`
+	Failures    int       `json:"failures"`
+	Error       string    `json:"error,omitempty"` // set while the last attempt was rejected
+}
+
+// Current returns the snapshot currently in effect (nil before Load).
+func Current() *Snapshot {
+	return store.Load()
+}
+
+// Get returns the configuration currently in effect (nil before Load).
+// Callers should not keep the result around if they want to observe reloads.
+func Get() *AppConfig {
+	if s := store.Load(); s != nil {
+		return s.Config
+	}
+	return nil
+}
+
+// LastReload returns a copy of the last reload status.
+func LastReload() ReloadStatus {
+	if st := status.Load(); st != nil {
+		return *st
+	}
+	return ReloadStatus{}
+}
+
+// Subscribe registers fn for future reloads and returns a function that removes it.
+func Subscribe(fn Subscriber) (cancel func()) {
+	subMu.Lock()
+	defer subMu.Unlock()
+	subID++
+	id := subID
+	subscribers[id] = fn
+	return func() {
+		subMu.Lock()
+		defer subMu.Unlock()
+		delete(subscribers, id)
+	}
+}
+
+// Reload loads the configuration again with the flags given to Load.
+// The new snapshot is published only if it validates; otherwise the previous
+// configuration stays in effect and the error is recorded in LastReload.
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	if loadFlags == nil {
+		return errors.New("configuration was never loaded")
+	}
+
+	st := LastReload()
+	st.LastAttempt = time.Now().UTC()
+
+	snap, err := load(loadFlags)
+	if err == nil {
+		err = snap.Config.Validate()
+	}
+	if err != nil {
+		st.Failures++
+		st.Error = err.Error()
+		status.Store(&st)
+		return err
+	}
+
+	old := store.Swap(snap)
+	st.File = snap.File
+	st.LastSuccess = snap.LoadedAt
+	st.Reloads++
+	st.Error = ""
+	status.Store(&st)
+
+	if old != nil {
+		notify(old.Config, snap.Config)
+	}
+	return nil
+}
+
+// publish installs the initial snapshot.
+func publish(snap *Snapshot) {
+	store.Store(snap)
+	status.Store(&ReloadStatus{
+		File:        snap.File,
+		LastAttempt: snap.LoadedAt,
+		LastSuccess: snap.LoadedAt,
+	})
+}
+
+func notify(old, new *AppConfig) {
+	subMu.Lock()
+	fns := make([]Subscriber, 0, len(subscribers))
+	for _, fn := range subscribers {
+		fns = append(fns, fn)
+	}
+	subMu.Unlock()
+
+	for _, fn := range fns {
+		fn(old, new)
+	}
+}
@@ -0,0 +1,416 @@
+package configuration
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// targetRoles are the roles a database target can be expected to have.
+var targetRoles = []string{"primary", "replica"}
+
+// sslModes are the libpq sslmode values accepted for DatabaseConfig.SSL.
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// clientAuthModes are the accepted TLSConfig.ClientAuth values.
+var clientAuthModes = []string{"require", "verify-if-given"}
+
+// dbTLSVersions are the accepted DatabaseTLSConfig.MinVersion values.
+var dbTLSVersions = []string{"1.2", "1.3"}
+
+// logLevels are the accepted LogConfig.Level values.
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// Problem is a single invalid setting.
+type Problem struct {
+	Key     string // setting key, e.g. "rest.port"
+	Message string
+}
+
+func (p Problem) String() string {
+	if env := settingByKey(p.Key).Env; env != "" {
+		return fmt.Sprintf("%s (%s): %s", p.Key, env, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Key, p.Message)
+}
+
+// ValidationError aggregates every Problem found by Validate.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problem(s)):", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+// problems collects failures while walking the configuration.
+type problems []Problem
+
+func (ps *problems) add(key, format string, args ...any) {
+	*ps = append(*ps, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the whole configuration, this host included (see HostProblems), and
+// returns a *ValidationError listing every problem, or nil when the configuration is usable.
+func (c *AppConfig) Validate() error {
+	ps := c.settingProblems()
+	ps = append(ps, c.HostProblems()...)
+	return ps.err()
+}
+
+// ValidateSettings is Validate without the checks of this host, for the commands that do
+// not serve (serve --dry-run and config validate in CI, migrate).
+func (c *AppConfig) ValidateSettings() error {
+	return c.settingProblems().err()
+}
+
+// HostProblems reports what the configuration needs from this host and does not find:
+// an existing, writable data_dir.
+func (c *AppConfig) HostProblems() []Problem {
+	var ps problems
+	if strings.TrimSpace(c.AppDataDir) != "" {
+		probeDataDir(&ps, c.AppDataDir)
+	}
+	return ps
+}
+
+func (ps problems) err() error {
+	if len(ps) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: ps}
+}
+
+// settingProblems checks the values themselves.
+func (c *AppConfig) settingProblems() problems {
+	var ps problems
+
+	if strings.TrimSpace(c.AppName) == "" {
+		ps.add("name", "must not be empty")
+	}
+	if strings.TrimSpace(c.Env) == "" {
+		ps.add("env", "must not be empty")
+	}
+	if strings.TrimSpace(c.AppDataDir) == "" {
+		ps.add("data_dir", "must not be empty")
+	}
+
+	if c.LogConfig != nil && !slices.Contains(logLevels, strings.ToLower(c.LogConfig.Level)) {
+		ps.add("log.level", "must be one of %s, got %q", strings.Join(logLevels, ", "), c.LogConfig.Level)
+	}
+
+	if c.RestConfig == nil {
+		ps.add("rest", "section is missing")
+	} else {
+		c.RestConfig.validate(&ps)
+	}
+	if c.DatabaseConfig == nil {
+		ps.add("db", "section is missing")
+	} else {
+		c.DatabaseConfig.validate(&ps)
+	}
+
+	if c.ChecksConfig != nil {
+		for _, u := range c.ChecksConfig.ServiceURLs {
+			if s := strings.TrimSpace(u); s != "" && !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+				ps.add("checks.service_urls", "%q is not an http(s) URL", s)
+			}
+		}
+	}
+
+	if c.FeaturesConfig != nil {
+		for name, f := range c.FeaturesConfig.Flags {
+			if f.Percentage != nil && (*f.Percentage < 0 || *f.Percentage > 100) {
+				ps.add("features.flags", "%s: percentage must be between 0 and 100, got %d", name, *f.Percentage)
+			}
+			for h := range f.Headers {
+				if strings.TrimSpace(h) == "" {
+					ps.add("features.flags", "%s: header targeting with an empty header name", name)
+				}
+			}
+		}
+	}
+
+	if c.LivenessConfig != nil {
+		if c.LivenessConfig.MaxGoroutines < 0 {
+			ps.add("liveness.max_goroutines", "must not be negative, got %d", c.LivenessConfig.MaxGoroutines)
+		}
+		if c.LivenessConfig.MaxMemoryMB < 0 {
+			ps.add("liveness.max_memory_mb", "must not be negative, got %d", c.LivenessConfig.MaxMemoryMB)
+		}
+	}
+	return ps
+}
+
+func (r *RestConfig) validate(ps *problems) {
+	if !validPort(r.Port) {
+		ps.add("rest.port", "must be between 1 and 65535, got %d", r.Port)
+	}
+	if !r.SinglePort {
+		if !validPort(r.AdminPort) {
+			ps.add("rest.admin_port", "must be between 1 and 65535, got %d", r.AdminPort)
+		} else if r.AdminPort == r.Port {
+			ps.add("rest.admin_port", "must differ from rest.port (%d), or set rest.single_port", r.Port)
+		}
+	}
+	for _, d := range []struct {
+		key string
+		val time.Duration
+	}{
+		{"rest.read_timeout", r.ReadTimeout},
+		{"rest.read_header_timeout", r.ReadHeaderTimeout},
+		{"rest.write_timeout", r.WriteTimeout},
+		{"rest.idle_timeout", r.IdleTimeout},
+		{"rest.pre_stop_delay", r.PreStopDelay},
+	} {
+		if d.val < 0 {
+			ps.add(d.key, "must not be negative, got %s", d.val)
+		}
+	}
+	if r.ShutdownTimeout <= 0 {
+		ps.add("rest.shutdown_timeout", "must be positive, got %s", r.ShutdownTimeout)
+	}
+	if r.UnixSocket != "" {
+		if _, err := strconv.ParseUint(r.UnixSocketMode, 8, 32); err != nil {
+			ps.add("rest.unix_socket_mode", "must be an octal file mode such as 0660, got %q", r.UnixSocketMode)
+		}
+		if owner := r.UnixSocketOwner; owner != "" {
+			if u, g, ok := strings.Cut(owner, ":"); !ok || u == "" || g == "" {
+				ps.add("rest.unix_socket_owner", "must be user:group or uid:gid, got %q", owner)
+			}
+		}
+	}
+	r.TLS.validate(ps)
+}
+
+func (t *TLSConfig) validate(ps *problems) {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		ps.add("rest.tls.cert_file", "rest.tls.cert_file and rest.tls.key_file must be set together")
+	}
+	if t.ClientCAFile != "" && !t.Enabled() {
+		ps.add("rest.tls.client_ca_file", "requires rest.tls.cert_file and rest.tls.key_file")
+	}
+	if !slices.Contains(clientAuthModes, t.ClientAuth) {
+		ps.add("rest.tls.client_auth", "must be one of %s, got %q", strings.Join(clientAuthModes, ", "), t.ClientAuth)
+	}
+	for _, f := range []struct{ key, path string }{
+		{"rest.tls.cert_file", t.CertFile},
+		{"rest.tls.key_file", t.KeyFile},
+		{"rest.tls.client_ca_file", t.ClientCAFile},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			ps.add(f.key, "%v", err)
+		}
+	}
+	if t.Enabled() && t.ReloadInterval <= 0 {
+		ps.add("rest.tls.reload_interval", "must be positive, got %s", t.ReloadInterval)
+	}
+}
+
+func (d *DatabaseConfig) validate(ps *problems) {
+	if d.SSL != "" && !slices.Contains(sslModes, d.SSL) {
+		ps.add("db.ssl", "must be one of %s, got %q", strings.Join(sslModes, ", "), d.SSL)
+	}
+	if d.Role != "" && !slices.Contains(targetRoles, d.Role) {
+		ps.add("db.role", "must be one of %s, got %q", strings.Join(targetRoles, ", "), d.Role)
+	}
+	if d.StatementTimeout < 0 {
+		ps.add("db.statement_timeout", "must not be negative, got %s", d.StatementTimeout)
+	}
+	d.TLS.validate(ps, d.SSL)
+	d.Pool.validate(ps)
+	if strings.TrimSpace(d.Migrations.Table) == "" {
+		ps.add("db.migrations.table", "must not be empty")
+	}
+	if d.Migrations.Timeout <= 0 {
+		ps.add("db.migrations.timeout", "must be positive, got %s", d.Migrations.Timeout)
+	}
+	d.Diagnostics.validate(ps)
+	if d.Trace.SlowThreshold < 0 {
+		ps.add("db.trace.slow_threshold", "must not be negative, got %s", d.Trace.SlowThreshold)
+	}
+	if d.Trace.SlowQueries < 1 {
+		ps.add("db.trace.slow_queries", "must be at least 1, got %d", d.Trace.SlowQueries)
+	}
+	d.Notify.validate(ps)
+	for _, name := range slices.Sorted(maps.Keys(d.Targets)) {
+		d.Targets[name].validate(ps, name)
+	}
+
+	if d.DSN != "" {
+		if d.Host != "" || d.Name != "" || d.Username != "" {
+			ps.add("db.dsn", "is mutually exclusive with db.host, db.name and db.username")
+		}
+		if _, err := pgconn.ParseConfig(d.DSN.Reveal()); err != nil {
+			ps.add("db.dsn", "%v", err) // pgconn redacts the password
+		}
+		return
+	}
+
+	if d.Host != "" {
+		if !validPort(d.Port) {
+			ps.add("db.port", "must be between 1 and 65535, got %d", d.Port)
+		}
+		if d.Name == "" {
+			ps.add("db.name", "is required when db.host is set")
+		}
+		if d.Username == "" {
+			ps.add("db.username", "is required when db.host is set")
+		}
+		if d.Password == "" {
+			ps.add("db.password", "is required when db.host is set")
+		}
+	}
+}
+
+func (n *NotifyConfig) validate(ps *problems) {
+	for _, ch := range n.Channels {
+		// PostgreSQL truncates identifiers to NAMEDATALEN-1 bytes: a longer name would LISTEN elsewhere
+		if ch == "" || len(ch) > 63 {
+			ps.add("db.notify.channels", "%q: must be 1 to 63 bytes long", ch)
+		}
+	}
+	if n.ClientBuffer < 1 {
+		ps.add("db.notify.client_buffer", "must be at least 1, got %d", n.ClientBuffer)
+	}
+	if n.MaxReconnectDelay < time.Second {
+		ps.add("db.notify.max_reconnect_delay", "must be at least 1s, got %s", n.MaxReconnectDelay)
+	}
+}
+
+func (t DatabaseTargetConfig) validate(ps *problems, name string) {
+	if name == PrimaryTarget || strings.TrimSpace(name) == "" {
+		ps.add("db.targets", "%q: name is reserved or empty", name)
+	}
+	if t.DSN == "" {
+		ps.add("db.targets", "%s: dsn is required", name)
+	} else if _, err := pgconn.ParseConfig(t.DSN.Reveal()); err != nil {
+		ps.add("db.targets", "%s: %v", name, err) // pgconn redacts the password
+	}
+	if t.Role != "" && !slices.Contains(targetRoles, t.Role) {
+		ps.add("db.targets", "%s: role must be one of %s, got %q", name, strings.Join(targetRoles, ", "), t.Role)
+	}
+}
+
+func (t *DatabaseTLSConfig) validate(ps *problems, sslMode string) {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		ps.add("db.tls.cert_file", "db.tls.cert_file and db.tls.key_file must be set together")
+	}
+	if sslMode == "disable" && (t.CAFile != "" || t.CertFile != "" || t.ServerName != "") {
+		ps.add("db.ssl", "is disable, db.tls settings would be ignored")
+	}
+	for _, f := range []struct{ key, path string }{
+		{"db.tls.ca_file", t.CAFile},
+		{"db.tls.cert_file", t.CertFile},
+		{"db.tls.key_file", t.KeyFile},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			ps.add(f.key, "%v", err)
+		}
+	}
+	if !slices.Contains(dbTLSVersions, t.MinVersion) {
+		ps.add("db.tls.min_version", "must be one of %s, got %q", strings.Join(dbTLSVersions, ", "), t.MinVersion)
+	}
+}
+
+func (p *PoolConfig) validate(ps *problems) {
+	if p.MaxConns < 1 {
+		ps.add("db.pool.max_conns", "must be at least 1, got %d", p.MaxConns)
+	}
+	if p.MinConns < 0 || p.MinConns > p.MaxConns {
+		ps.add("db.pool.min_conns", "must be between 0 and db.pool.max_conns (%d), got %d", p.MaxConns, p.MinConns)
+	}
+	for _, d := range []struct {
+		key string
+		val time.Duration
+	}{
+		{"db.pool.max_conn_lifetime", p.MaxConnLifetime},
+		{"db.pool.max_conn_idle_time", p.MaxConnIdleTime},
+		{"db.pool.health_check_period", p.HealthCheckPeriod},
+	} {
+		if d.val <= 0 {
+			ps.add(d.key, "must be positive, got %s", d.val)
+		}
+	}
+}
+
+func (g *DiagnosticsConfig) validate(ps *problems) {
+	for _, t := range []struct {
+		warnKey, critKey string
+		warn, crit       float64
+		unit             string
+		lowerIsWorse     bool // the cache hit ratio: critical is below warning
+	}{
+		{"db.diagnostics.connections_warning_pct", "db.diagnostics.connections_critical_pct", float64(g.ConnectionsWarningPct), float64(g.ConnectionsCriticalPct), "%", false},
+		{"db.diagnostics.replica_lag_warning", "db.diagnostics.replica_lag_critical", g.ReplicaLagWarning.Seconds(), g.ReplicaLagCritical.Seconds(), "s", false},
+		{"db.diagnostics.size_warning_mb", "db.diagnostics.size_critical_mb", float64(g.SizeWarningMB), float64(g.SizeCriticalMB), "MiB", false},
+		{"db.diagnostics.long_query_warning", "db.diagnostics.long_query_critical", g.LongQueryWarning.Seconds(), g.LongQueryCritical.Seconds(), "s", false},
+		{"db.diagnostics.idle_in_tx_warning", "db.diagnostics.idle_in_tx_critical", g.IdleInTxWarning.Seconds(), g.IdleInTxCritical.Seconds(), "s", false},
+		{"db.diagnostics.lock_wait_warning", "db.diagnostics.lock_wait_critical", g.LockWaitWarning.Seconds(), g.LockWaitCritical.Seconds(), "s", false},
+		{"db.diagnostics.cache_hit_warning_pct", "db.diagnostics.cache_hit_critical_pct", float64(g.CacheHitWarningPct), float64(g.CacheHitCriticalPct), "%", true},
+		{"db.diagnostics.wraparound_warning_pct", "db.diagnostics.wraparound_critical_pct", float64(g.WraparoundWarningPct), float64(g.WraparoundCriticalPct), "%", false},
+	} {
+		for _, v := range []struct {
+			key string
+			val float64
+		}{{t.warnKey, t.warn}, {t.critKey, t.crit}} {
+			switch {
+			case t.unit == "%" && (v.val < 0 || v.val > 100):
+				ps.add(v.key, "must be between 0 (disabled) and 100, got %v", v.val)
+			case v.val < 0:
+				ps.add(v.key, "must not be negative, got %v%s", v.val, t.unit)
+			}
+		}
+		if t.warn <= 0 || t.crit <= 0 {
+			continue
+		}
+		if t.lowerIsWorse && t.crit > t.warn {
+			ps.add(t.critKey, "must not be above %s", t.warnKey)
+		}
+		if !t.lowerIsWorse && t.crit < t.warn {
+			ps.add(t.critKey, "must not be below %s", t.warnKey)
+		}
+	}
+}
+
+// probeDataDir requires an existing, writable directory.
+func probeDataDir(ps *problems, dir string) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		ps.add("data_dir", "%q does not exist or is not accessible: %v", dir, err)
+		return
+	}
+	if !fi.IsDir() {
+		ps.add("data_dir", "%q is not a directory", dir)
+		return
+	}
+	f, err := os.CreateTemp(dir, ".config-validate-*")
+	if err != nil {
+		ps.add("data_dir", "%q is not writable: %v", dir, err)
+		return
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+func validPort(p int) bool {
+	return p > 0 && p <= 65535
+}
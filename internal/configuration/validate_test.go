@@ -0,0 +1,143 @@
+package configuration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// validConfig is the default configuration with a usable data_dir.
+func validConfig(t *testing.T) *AppConfig {
+	t.Helper()
+	snap, err := loadIn(t, nil, "--data-dir", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snap.Config
+}
+
+func problemKeys(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return []string{}
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error %v is not a *ValidationError", err)
+	}
+	keys := []string{}
+	for _, p := range verr.Problems {
+		keys = append(keys, p.Key)
+	}
+	return keys
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *AppConfig)
+		want   []string // keys of the problems, in report order
+	}{
+		{name: "defaults", modify: func(*AppConfig) {}, want: []string{}},
+		{
+			name: "every problem at once",
+			modify: func(c *AppConfig) {
+				c.AppName = " "
+				c.LogConfig.Level = "verbose"
+				c.RestConfig.Port = 0
+				c.RestConfig.ShutdownTimeout = 0
+				c.DatabaseConfig.SSL = "sometimes"
+				c.ChecksConfig.ServiceURLs = []string{"ftp://example.com"}
+			},
+			want: []string{"name", "log.level", "rest.port", "rest.shutdown_timeout", "db.ssl", "checks.service_urls"},
+		},
+		{
+			name:   "admin port equal to the port",
+			modify: func(c *AppConfig) { c.RestConfig.AdminPort = c.RestConfig.Port },
+			want:   []string{"rest.admin_port"},
+		},
+		{
+			name: "admin port ignored with single_port",
+			modify: func(c *AppConfig) {
+				c.RestConfig.SinglePort = true
+				c.RestConfig.AdminPort = c.RestConfig.Port
+			},
+			want: []string{},
+		},
+		{
+			name:   "empty data_dir",
+			modify: func(c *AppConfig) { c.AppDataDir = "" },
+			want:   []string{"data_dir"},
+		},
+		{
+			name:   "missing data_dir",
+			modify: func(c *AppConfig) { c.AppDataDir = filepath.Join(c.AppDataDir, "missing") },
+			want:   []string{"data_dir"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.modify(cfg)
+			if got := problemKeys(t, cfg.Validate()); !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() problems = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorReport(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.RestConfig.Port = 70000
+	cfg.LogConfig.Level = "loud"
+	err := cfg.Validate()
+	want := "invalid configuration (2 problem(s)):\n" +
+		`  - log.level (APP_LOG_LEVEL): must be one of debug, info, warn, error, got "loud"` + "\n" +
+		"  - rest.port (APP_REST_PORT): must be between 1 and 65535, got 70000"
+	if err == nil || err.Error() != want {
+		t.Errorf("Validate() =\n%v\nwant\n%s", err, want)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	readOnly := t.TempDir()
+	if err := os.Chmod(readOnly, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		dataDir string
+		wantMsg string // of the host problem, "" when there is none
+	}{
+		{name: "writable", dataDir: t.TempDir()},
+		{name: "missing", dataDir: filepath.Join(readOnly, "missing"), wantMsg: "does not exist"},
+		{name: "not a directory", dataDir: file, wantMsg: "is not a directory"},
+		{name: "read-only", dataDir: readOnly, wantMsg: "is not writable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "read-only" && os.Geteuid() == 0 {
+				t.Skip("root writes to read-only directories")
+			}
+			cfg := validConfig(t)
+			cfg.AppDataDir = tt.dataDir
+			if err := cfg.ValidateSettings(); err != nil {
+				t.Errorf("ValidateSettings() = %v, want nil: data_dir is a host check", err)
+			}
+			host := cfg.HostProblems()
+			if (len(host) != 0) != (tt.wantMsg != "") || (len(host) == 1 && !strings.Contains(host[0].Message, tt.wantMsg)) {
+				t.Errorf("HostProblems() = %v, want %q", host, tt.wantMsg)
+			}
+			if got := problemKeys(t, cfg.Validate()); (len(got) != 0) != (tt.wantMsg != "") {
+				t.Errorf("Validate() problems = %v", got)
+			}
+		})
+	}
+}
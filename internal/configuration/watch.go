@@ -0,0 +1,121 @@
+package configuration
+
+import (
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce groups the burst of events an editor or a ConfigMap update produces.
+const reloadDebounce = 250 * time.Millisecond
+
+// Watcher reloads the configuration whenever one of the files it was read from changes.
+type Watcher struct {
+	fsw  *fsnotify.Watcher
+	dirs map[string]bool
+	done chan struct{}
+}
+
+// Watch starts watching the files backing the current snapshot.
+// It returns a nil Watcher when the configuration was not read from any file.
+func Watch() (*Watcher, error) {
+	snap := Current()
+	if snap == nil || len(snap.watchedFiles()) == 0 {
+		return nil, nil
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{fsw: fsw, dirs: map[string]bool{}, done: make(chan struct{})}
+	if err := w.sync(snap); err != nil {
+		_ = fsw.Close()
+		return nil, err
+	}
+	go w.loop()
+	return w, nil
+}
+
+// Stop ends the watch loop. It is safe to call on a nil Watcher.
+func (w *Watcher) Stop() error {
+	if w == nil {
+		return nil
+	}
+	close(w.done)
+	return w.fsw.Close()
+}
+
+// sync watches the parent directory of every file: Kubernetes updates ConfigMap
+// and Secret volumes by swapping a "..data" symlink, which never touches the file itself.
+func (w *Watcher) sync(snap *Snapshot) error {
+	for _, f := range snap.watchedFiles() {
+		dir := filepath.Dir(f)
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.fsw.Add(dir); err != nil {
+			return err
+		}
+		w.dirs[dir] = true
+	}
+	return nil
+}
+
+func (w *Watcher) loop() {
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if w.relevant(ev) {
+				debounce = time.After(reloadDebounce)
+			}
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("configuration watcher error", "error", err)
+		case <-debounce:
+			debounce = nil
+			if err := Reload(); err != nil {
+				slog.Error("configuration reload rejected, keeping previous configuration", "error", err)
+				continue
+			}
+			slog.Info("configuration reloaded", "file", Current().File)
+			if err := w.sync(Current()); err != nil {
+				slog.Warn("configuration watcher cannot follow new files", "error", err)
+			}
+		}
+	}
+}
+
+func (w *Watcher) relevant(ev fsnotify.Event) bool {
+	if ev.Op == fsnotify.Chmod {
+		return false
+	}
+	if filepath.Base(ev.Name) == "..data" {
+		return true
+	}
+	for _, f := range Current().watchedFiles() {
+		if filepath.Clean(ev.Name) == filepath.Clean(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchedFiles lists the files a reload depends on: the config file and the secret files.
+func (s *Snapshot) watchedFiles() []string {
+	files := append([]string(nil), s.SecretFiles...)
+	if s.File != "" {
+		files = append(files, s.File)
+	}
+	return files
+}
@@ -0,0 +1,40 @@
+// Package domain holds the business types shared by the handlers and the repositories,
+// and the errors the handlers turn into HTTP statuses.
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound means the entity does not exist (404).
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the change clashes with existing data, e.g. a duplicate key (409).
+	ErrConflict = errors.New("conflict")
+	// ErrInvalid means the change breaks a rule of the data: missing value, unknown reference, ... (422).
+	ErrInvalid = errors.New("invalid")
+)
+
+// ConstraintError is a change rejected by a database constraint. It wraps ErrConflict or
+// ErrInvalid, so errors.Is works on the kind while the handler can still name the field.
+type ConstraintError struct {
+	Kind       error  // ErrConflict or ErrInvalid
+	Constraint string // e.g. tasks_title_check
+	Table      string
+	Column     string // set for not null violations
+	Reason     string // e.g. "already exists"
+}
+
+func (e *ConstraintError) Error() string {
+	switch {
+	case e.Column != "":
+		return fmt.Sprintf("%v: %s.%s %s", e.Kind, e.Table, e.Column, e.Reason)
+	case e.Constraint != "":
+		return fmt.Sprintf("%v: %s (%s)", e.Kind, e.Reason, e.Constraint)
+	default:
+		return fmt.Sprintf("%v: %s", e.Kind, e.Reason)
+	}
+}
+
+func (e *ConstraintError) Unwrap() error { return e.Kind }
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Task is the example entity of the archetype (see examples/tasks).
+type Task struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Done      bool      `json:"done"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TaskRepository stores tasks. Methods return ErrNotFound for a missing task and a
+// *ConstraintError for a change the database rejects.
+type TaskRepository interface {
+	List(ctx context.Context) ([]Task, error)
+	Get(ctx context.Context, id int64) (Task, error)
+	Create(ctx context.Context, title string) (Task, error)
+	// Update loads the task, applies fn and saves it, atomically.
+	Update(ctx context.Context, id int64, fn func(*Task) error) (Task, error)
+	Delete(ctx context.Context, id int64) error
+}
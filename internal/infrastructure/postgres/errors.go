@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/khedhrije/tools-archetype/internal/domain"
+)
+
+// SQLSTATE codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	codeNotNullViolation     = "23502"
+	codeForeignKeyViolation  = "23503"
+	codeUniqueViolation      = "23505"
+	codeCheckViolation       = "23514"
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+)
+
+// MapError translates what repositories get from pgx into domain errors: pgx.ErrNoRows
+// becomes domain.ErrNotFound, and constraint violations a *domain.ConstraintError
+// (unique: domain.ErrConflict; foreign key, not null, check: domain.ErrInvalid).
+// Other errors are returned as they are.
+func MapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	ce := &domain.ConstraintError{Constraint: pgErr.ConstraintName, Table: pgErr.TableName}
+	switch pgErr.Code {
+	case codeUniqueViolation:
+		ce.Kind, ce.Reason = domain.ErrConflict, "already exists"
+	case codeForeignKeyViolation:
+		ce.Kind, ce.Reason = domain.ErrInvalid, "references a missing row, or is still referenced"
+	case codeNotNullViolation:
+		ce.Kind, ce.Column, ce.Reason = domain.ErrInvalid, pgErr.ColumnName, "is required"
+	case codeCheckViolation:
+		ce.Kind, ce.Reason = domain.ErrInvalid, "fails a check"
+	default:
+		return err
+	}
+	return ce
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/khedhrije/tools-archetype/internal/domain"
+)
+
+func TestMapError(t *testing.T) {
+	other := errors.New("connection reset")
+	tests := []struct {
+		name     string
+		err      error
+		wantKind error                   // errors.Is target, nil when err is returned as is
+		want     *domain.ConstraintError // nil when no constraint error is expected
+	}{
+		{name: "nil", err: nil},
+		{name: "no rows", err: pgx.ErrNoRows, wantKind: domain.ErrNotFound},
+		{name: "wrapped no rows", err: fmt.Errorf("get task: %w", pgx.ErrNoRows), wantKind: domain.ErrNotFound},
+		{
+			name:     "unique violation",
+			err:      &pgconn.PgError{Code: codeUniqueViolation, ConstraintName: "tasks_title_key", TableName: "tasks"},
+			wantKind: domain.ErrConflict,
+			want:     &domain.ConstraintError{Kind: domain.ErrConflict, Constraint: "tasks_title_key", Table: "tasks", Reason: "already exists"},
+		},
+		{
+			name:     "foreign key violation",
+			err:      &pgconn.PgError{Code: codeForeignKeyViolation, ConstraintName: "tasks_owner_fkey", TableName: "tasks"},
+			wantKind: domain.ErrInvalid,
+			want: &domain.ConstraintError{Kind: domain.ErrInvalid, Constraint: "tasks_owner_fkey", Table: "tasks",
+				Reason: "references a missing row, or is still referenced"},
+		},
+		{
+			name:     "not null violation",
+			err:      &pgconn.PgError{Code: codeNotNullViolation, TableName: "tasks", ColumnName: "title"},
+			wantKind: domain.ErrInvalid,
+			want:     &domain.ConstraintError{Kind: domain.ErrInvalid, Table: "tasks", Column: "title", Reason: "is required"},
+		},
+		{
+			name:     "check violation",
+			err:      fmt.Errorf("insert: %w", &pgconn.PgError{Code: codeCheckViolation, ConstraintName: "tasks_title_check", TableName: "tasks"}),
+			wantKind: domain.ErrInvalid,
+			want:     &domain.ConstraintError{Kind: domain.ErrInvalid, Constraint: "tasks_title_check", Table: "tasks", Reason: "fails a check"},
+		},
+		{name: "other SQLSTATE", err: &pgconn.PgError{Code: codeSerializationFailure}},
+		{name: "not a PostgreSQL error", err: other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MapError(tt.err)
+			if tt.wantKind == nil {
+				if got != tt.err {
+					t.Errorf("MapError() = %v, want the error as it is", got)
+				}
+				return
+			}
+			if !errors.Is(got, tt.wantKind) {
+				t.Errorf("MapError() = %v, want %v", got, tt.wantKind)
+			}
+			var ce *domain.ConstraintError
+			if errors.As(got, &ce) != (tt.want != nil) {
+				t.Fatalf("MapError() = %#v, want a *domain.ConstraintError: %v", got, tt.want != nil)
+			}
+			if tt.want != nil && *ce != *tt.want {
+				t.Errorf("MapError() = %+v, want %+v", *ce, *tt.want)
+			}
+		})
+	}
+}
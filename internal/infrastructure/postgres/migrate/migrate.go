@@ -0,0 +1,431 @@
+// Package migrate applies versioned SQL migrations to PostgreSQL.
+//
+// Migrations are read from an fs.FS (usually embedded) as <version>_<name>.up.sql and
+// the optional <version>_<name>.down.sql. Each one runs in its own transaction and is
+// recorded, with the checksum of its up script, in the migrations table. Runs are
+// serialized across replicas with a session-level advisory lock.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrDrift means an applied migration's up script changed since it was applied.
+	ErrDrift = errors.New("applied migration changed")
+	// ErrPending means migrations embedded in the binary are not applied yet.
+	ErrPending = errors.New("pending migrations")
+	// ErrIrreversible means a migration to roll back has no down script.
+	ErrIrreversible = errors.New("migration has no down script")
+	// ErrUnknownVersion means a version is neither 0 nor one of the migrations.
+	ErrUnknownVersion = errors.New("unknown migration version")
+)
+
+// fileName matches 0001_create_tasks.up.sql and 0001_create_tasks.down.sql.
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string // empty when the migration cannot be rolled back
+	Checksum string // sha256 of Up
+}
+
+// State of a migration in a Status.
+type State string
+
+const (
+	StateApplied State = "applied"
+	StatePending State = "pending"
+	StateDrifted State = "drifted" // applied, but the up script changed since
+	StateUnknown State = "unknown" // applied, but not in this binary (applied by a newer release)
+)
+
+// Entry is the state of one migration.
+type Entry struct {
+	Version   int64     `json:"version"`
+	Name      string    `json:"name"`
+	State     State     `json:"state"`
+	AppliedAt time.Time `json:"appliedAt,omitzero"`
+	Duration  string    `json:"duration,omitempty"` // how long applying took
+}
+
+// Status compares the migrations of the binary with those recorded in the database.
+type Status struct {
+	Current    int64   `json:"current"` // highest applied version, 0 when none
+	Latest     int64   `json:"latest"`  // highest version in the binary
+	Pending    int     `json:"pending"`
+	Drifted    int     `json:"drifted"`
+	Migrations []Entry `json:"migrations"` // by version
+}
+
+// Err reports drift first, then pending migrations; unknown versions are tolerated
+// so that instances of the previous release stay ready during a rolling update.
+func (s Status) Err() error {
+	switch {
+	case s.Drifted > 0:
+		return fmt.Errorf("%w: %d migration(s)", ErrDrift, s.Drifted)
+	case s.Pending > 0:
+		return fmt.Errorf("%w: %d migration(s), schema at %d, binary at %d", ErrPending, s.Pending, s.Current, s.Latest)
+	}
+	return nil
+}
+
+// applied is a row of the migrations table.
+type applied struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+	duration  time.Duration
+}
+
+// Migrator applies the migrations of a source to the database behind a pool.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration // by version
+	table      string      // as configured, e.g. "schema_migrations" or "app.schema_migrations"
+	lockID     int64
+}
+
+// New reads the migrations of source, recorded in table (optionally schema-qualified).
+func New(pool *pgxpool.Pool, source fs.FS, table string) (*Migrator, error) {
+	migrations, err := read(source)
+	if err != nil {
+		return nil, err
+	}
+	h := fnv.New64a()
+	h.Write([]byte("migrate:" + table))
+	return &Migrator{
+		pool:       pool,
+		migrations: migrations,
+		table:      table,
+		lockID:     int64(h.Sum64()),
+	}, nil
+}
+
+func read(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: version must be a positive integer", e.Name())
+		}
+		b, err := fs.ReadFile(source, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", e.Name(), err)
+		}
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(b)
+			sum := sha256.Sum256(b)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(b)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up script", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	slices.SortFunc(out, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return out, nil
+}
+
+// Migrations returns the migrations of the source, by version.
+func (m *Migrator) Migrations() []Migration {
+	return slices.Clone(m.migrations)
+}
+
+// Latest returns the highest version of the source, 0 when it is empty.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status reads the migrations table without taking the lock. A missing table means nothing is applied.
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	var exists bool
+	// to_regclass parses its argument like a name in SQL: quote it as the other statements do, or a
+	// mixed-case table would be looked up in lower case.
+	if err := m.pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", m.ident()).Scan(&exists); err != nil {
+		return Status{}, err
+	}
+	var done map[int64]applied
+	if exists {
+		var err error
+		if done, err = m.applied(ctx, m.pool); err != nil {
+			return Status{}, err
+		}
+	}
+	return m.status(done), nil
+}
+
+func (m *Migrator) status(done map[int64]applied) Status {
+	st := Status{Latest: m.Latest()}
+	known := map[int64]bool{}
+	for _, mig := range m.migrations {
+		known[mig.Version] = true
+		e := Entry{Version: mig.Version, Name: mig.Name, State: StatePending}
+		if a, ok := done[mig.Version]; ok {
+			e.State, e.AppliedAt, e.Duration = StateApplied, a.appliedAt, a.duration.String()
+			if a.checksum != mig.Checksum {
+				e.State = StateDrifted
+				st.Drifted++
+			}
+		} else {
+			st.Pending++
+		}
+		st.Migrations = append(st.Migrations, e)
+	}
+	for v, a := range done {
+		st.Current = max(st.Current, v)
+		if !known[v] {
+			st.Migrations = append(st.Migrations, Entry{Version: v, Name: a.name, State: StateUnknown, AppliedAt: a.appliedAt, Duration: a.duration.String()})
+		}
+	}
+	slices.SortFunc(st.Migrations, func(a, b Entry) int { return cmp.Compare(a.Version, b.Version) })
+	return st
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the last steps applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+	return m.locked(ctx, func(conn *pgxpool.Conn, done map[int64]applied) error {
+		return m.migrate(ctx, conn, done, downTarget(done, steps))
+	})
+}
+
+// downTarget is the version left applied once the last steps applied migrations are rolled back.
+func downTarget(done map[int64]applied, steps int) int64 {
+	versions := make([]int64, 0, len(done))
+	for v := range done {
+		versions = append(versions, v)
+	}
+	slices.Sort(versions)
+	if steps >= len(versions) {
+		return 0
+	}
+	return versions[len(versions)-1-steps]
+}
+
+// To applies or rolls back migrations until version is the last one applied (0 rolls back everything).
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && !slices.ContainsFunc(m.migrations, func(mig Migration) bool { return mig.Version == version }) {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return m.locked(ctx, func(conn *pgxpool.Conn, done map[int64]applied) error {
+		return m.migrate(ctx, conn, done, version)
+	})
+}
+
+// locked runs fn on a dedicated connection holding the advisory lock, once the
+// migrations table exists and no applied migration has drifted.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn, done map[int64]applied) error) (err error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	// The lock and the setting belong to the session: a connection that cannot
+	// release them must not go back to the pool. Closed, it is dropped on Release.
+	cleanup := context.WithoutCancel(ctx)
+
+	// Migrations and the wait for the lock must not be cut by db.statement_timeout.
+	if _, err := conn.Exec(ctx, "SET statement_timeout = 0"); err != nil {
+		return err
+	}
+	defer func() {
+		if conn.Conn().IsClosed() {
+			return
+		}
+		if _, rerr := conn.Exec(cleanup, "RESET statement_timeout"); rerr != nil {
+			_ = conn.Conn().Close(cleanup)
+		}
+	}()
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", m.lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, uerr := conn.Exec(cleanup, "SELECT pg_advisory_unlock($1)", m.lockID); uerr != nil {
+			_ = conn.Conn().Close(cleanup)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version      bigint PRIMARY KEY,
+	name         text NOT NULL,
+	checksum     text NOT NULL,
+	applied_at   timestamptz NOT NULL DEFAULT now(),
+	execution_ms bigint NOT NULL
+)`, m.ident())); err != nil {
+		return fmt.Errorf("create migrations table: %w", err)
+	}
+	done, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := m.status(done).Err(); errors.Is(err, ErrDrift) {
+		return err
+	}
+	return fn(conn, done)
+}
+
+// migrate runs the plan to target.
+func (m *Migrator) migrate(ctx context.Context, conn *pgxpool.Conn, done map[int64]applied, target int64) error {
+	down, up, err := m.plan(done, target)
+	if err != nil {
+		return err
+	}
+	for _, mig := range down {
+		if err := m.apply(ctx, conn, mig, false); err != nil {
+			return err
+		}
+	}
+	for _, mig := range up {
+		if err := m.apply(ctx, conn, mig, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plan lists the applied migrations above target to roll back, newest first, then the
+// pending ones up to target to apply, oldest first. It fails before anything runs when
+// one to roll back is unknown or has no down script.
+func (m *Migrator) plan(done map[int64]applied, target int64) (down, up []Migration, err error) {
+	byVersion := map[int64]Migration{}
+	for _, mig := range m.migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	var versions []int64
+	for v := range done {
+		if v > target {
+			versions = append(versions, v)
+		}
+	}
+	slices.Sort(versions)
+	slices.Reverse(versions)
+	for _, v := range versions {
+		mig, ok := byVersion[v]
+		if !ok {
+			return nil, nil, fmt.Errorf("roll back %d: %w (applied by a newer release)", v, ErrUnknownVersion)
+		}
+		if mig.Down == "" {
+			return nil, nil, fmt.Errorf("roll back %d_%s: %w", v, mig.Name, ErrIrreversible)
+		}
+		down = append(down, mig)
+	}
+
+	for _, mig := range m.migrations {
+		if _, ok := done[mig.Version]; ok || mig.Version > target {
+			continue
+		}
+		up = append(up, mig)
+	}
+	return down, up, nil
+}
+
+// apply runs one script and records it in the same transaction.
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, mig Migration, up bool) error {
+	direction, script := "up", mig.Up
+	if !up {
+		direction, script = "down", mig.Down
+	}
+	start := time.Now()
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, script); err != nil {
+			return err
+		}
+		if !up {
+			_, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE version = $1", m.ident()), mig.Version)
+			return err
+		}
+		_, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO %s (version, name, checksum, execution_ms) VALUES ($1, $2, $3, $4)", m.ident()),
+			mig.Version, mig.Name, mig.Checksum, time.Since(start).Milliseconds())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s %s: %w", mig.Version, mig.Name, direction, err)
+	}
+	slog.Info("migration applied", "version", mig.Version, "name", mig.Name, "direction", direction, "took", time.Since(start))
+	return nil
+}
+
+// querier is satisfied by the pool and by a connection.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func (m *Migrator) applied(ctx context.Context, q querier) (map[int64]applied, error) {
+	rows, err := q.Query(ctx, fmt.Sprintf("SELECT version, name, checksum, applied_at, execution_ms FROM %s", m.ident()))
+	if err != nil {
+		return nil, fmt.Errorf("read migrations table: %w", err)
+	}
+	defer rows.Close()
+	done := map[int64]applied{}
+	for rows.Next() {
+		var (
+			a  applied
+			ms int64
+		)
+		if err := rows.Scan(&a.version, &a.name, &a.checksum, &a.appliedAt, &ms); err != nil {
+			return nil, err
+		}
+		a.duration = time.Duration(ms) * time.Millisecond
+		done[a.version] = a
+	}
+	return done, rows.Err()
+}
+
+// ident quotes the table name, keeping an optional schema qualifier.
+func (m *Migrator) ident() string {
+	return pgx.Identifier(strings.Split(m.table, ".")).Sanitize()
+}
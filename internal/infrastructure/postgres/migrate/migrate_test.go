@@ -0,0 +1,266 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func file(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		want     []string // version_name, with "+down" when it has a down script
+		wantErr  string
+		checksum map[int64]string
+	}{
+		{
+			name:  "empty",
+			files: fstest.MapFS{"README.md": file("# Migrations"), "migrations.go": file("package migrations")},
+			want:  []string{},
+		},
+		{
+			name: "sorted by version, down optional, other files ignored",
+			files: fstest.MapFS{
+				"0010_add_index.up.sql":      file("CREATE INDEX"),
+				"0002_create_users.up.sql":   file("CREATE TABLE users"),
+				"0002_create_users.down.sql": file("DROP TABLE users"),
+				"1_first.up.sql":             file("SELECT 1"),
+				"notes.sql":                  file("-- not a migration"),
+				"0003_draft.up.sql.bak":      file("SELECT 3"),
+				"sub/0004_nested.up.sql":     file("SELECT 4"),
+			},
+			want: []string{"1_first", "2_create_users+down", "10_add_index"},
+			checksum: map[int64]string{
+				// sha256("SELECT 1")
+				1: "e004ebd5b5532a4b85984a62f8ad48a81aa3460c1ca07701f386135d72cdecf5",
+			},
+		},
+		{
+			name:    "version 0",
+			files:   fstest.MapFS{"0000_zero.up.sql": file("SELECT 0")},
+			wantErr: "version must be a positive integer",
+		},
+		{
+			name:    "version overflow",
+			files:   fstest.MapFS{"99999999999999999999_big.up.sql": file("SELECT 0")},
+			wantErr: "version must be a positive integer",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"0001_a.up.sql":   file("SELECT 1"),
+				"0001_b.down.sql": file("SELECT 1"),
+			},
+			wantErr: `conflicting names "a" and "b"`,
+		},
+		{
+			name: "same version twice",
+			files: fstest.MapFS{
+				"0001_a.up.sql": file("SELECT 1"),
+				"1_b.up.sql":    file("SELECT 1"),
+			},
+			wantErr: "conflicting names",
+		},
+		{
+			name:    "down without up",
+			files:   fstest.MapFS{"0001_a.down.sql": file("SELECT 1")},
+			wantErr: "migration 1_a: missing up script",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := read(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("read() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("read() error = %v", err)
+			}
+			got := []string{}
+			for _, m := range migrations {
+				s := strings.Join([]string{itoa(m.Version), m.Name}, "_")
+				if m.Down != "" {
+					s += "+down"
+				}
+				got = append(got, s)
+				if want, ok := tt.checksum[m.Version]; ok && m.Checksum != want {
+					t.Errorf("checksum of %d = %s, want %s", m.Version, m.Checksum, want)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("read() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testMigrator has the migrations 1 to 4; 3 has no down script.
+func testMigrator(t *testing.T) *Migrator {
+	t.Helper()
+	m, err := New(nil, fstest.MapFS{
+		"0001_a.up.sql": file("1"), "0001_a.down.sql": file("-1"),
+		"0002_b.up.sql": file("2"), "0002_b.down.sql": file("-2"),
+		"0003_c.up.sql": file("3"),
+		"0004_d.up.sql": file("4"), "0004_d.down.sql": file("-4"),
+	}, "schema_migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// appliedUpTo records the given versions as applied with their current checksums.
+func appliedUpTo(m *Migrator, versions ...int64) map[int64]applied {
+	done := map[int64]applied{}
+	for _, v := range versions {
+		a := applied{version: v, name: "unknown", checksum: "?"}
+		for _, mig := range m.migrations {
+			if mig.Version == v {
+				a.name, a.checksum = mig.Name, mig.Checksum
+			}
+		}
+		done[v] = a
+	}
+	return done
+}
+
+func versionsOf(migrations []Migration) []int64 {
+	out := []int64{}
+	for _, m := range migrations {
+		out = append(out, m.Version)
+	}
+	return out
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		applied  []int64
+		target   int64
+		wantDown []int64
+		wantUp   []int64
+		wantErr  error
+	}{
+		{name: "up from scratch", target: 4, wantDown: []int64{}, wantUp: []int64{1, 2, 3, 4}},
+		{name: "up to a version", target: 2, wantDown: []int64{}, wantUp: []int64{1, 2}},
+		{name: "up to date", applied: []int64{1, 2, 3, 4}, target: 4, wantDown: []int64{}, wantUp: []int64{}},
+		{name: "fills a gap", applied: []int64{1, 3}, target: 4, wantDown: []int64{}, wantUp: []int64{2, 4}},
+		{name: "down one", applied: []int64{1, 2, 3, 4}, target: 3, wantDown: []int64{4}, wantUp: []int64{}},
+		{name: "down newest first", applied: []int64{1, 2}, target: 0, wantDown: []int64{2, 1}, wantUp: []int64{}},
+		{name: "down through an irreversible one", applied: []int64{1, 2, 3, 4}, target: 2, wantErr: ErrIrreversible},
+		{name: "down through an unknown one", applied: []int64{1, 2, 9}, target: 2, wantErr: ErrUnknownVersion},
+		{name: "unknown one below target is kept", applied: []int64{1, 9}, target: 10, wantDown: []int64{}, wantUp: []int64{2, 3, 4}},
+	}
+	m := testMigrator(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			down, up, err := m.plan(appliedUpTo(m, tt.applied...), tt.target)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("plan() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("plan() error = %v", err)
+			}
+			if got := versionsOf(down); !slices.Equal(got, tt.wantDown) {
+				t.Errorf("down = %v, want %v", got, tt.wantDown)
+			}
+			if got := versionsOf(up); !slices.Equal(got, tt.wantUp) {
+				t.Errorf("up = %v, want %v", got, tt.wantUp)
+			}
+		})
+	}
+}
+
+func TestDownTarget(t *testing.T) {
+	tests := []struct {
+		applied []int64
+		steps   int
+		want    int64
+	}{
+		{applied: []int64{1, 2, 4}, steps: 1, want: 2},
+		{applied: []int64{4, 1, 2}, steps: 2, want: 1},
+		{applied: []int64{1, 2, 4}, steps: 3, want: 0},
+		{applied: []int64{1, 2, 4}, steps: 10, want: 0},
+		{applied: nil, steps: 1, want: 0},
+	}
+	m := testMigrator(t)
+	for _, tt := range tests {
+		if got := downTarget(appliedUpTo(m, tt.applied...), tt.steps); got != tt.want {
+			t.Errorf("downTarget(%v, %d) = %d, want %d", tt.applied, tt.steps, got, tt.want)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	m := testMigrator(t)
+	done := appliedUpTo(m, 1, 2, 7)
+	a := done[2]
+	a.checksum = "changed"
+	done[2] = a
+
+	st := m.status(done)
+	if st.Current != 7 || st.Latest != 4 || st.Pending != 2 || st.Drifted != 1 {
+		t.Errorf("status() = current %d, latest %d, pending %d, drifted %d; want 7, 4, 2, 1",
+			st.Current, st.Latest, st.Pending, st.Drifted)
+	}
+	var states []string
+	for _, e := range st.Migrations {
+		states = append(states, itoa(e.Version)+":"+string(e.State))
+	}
+	want := []string{"1:applied", "2:drifted", "3:pending", "4:pending", "7:unknown"}
+	if !slices.Equal(states, want) {
+		t.Errorf("status() migrations = %v, want %v", states, want)
+	}
+	if err := st.Err(); !errors.Is(err, ErrDrift) {
+		t.Errorf("Err() = %v, want ErrDrift first", err)
+	}
+
+	st = m.status(appliedUpTo(m, 1))
+	if err := st.Err(); !errors.Is(err, ErrPending) {
+		t.Errorf("Err() = %v, want ErrPending", err)
+	}
+	// Versions of a newer release do not fail readiness.
+	st = m.status(appliedUpTo(m, 1, 2, 3, 4, 5))
+	if err := st.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil with an unknown version", err)
+	}
+}
+
+func TestArguments(t *testing.T) {
+	m := testMigrator(t)
+	if err := m.To(context.Background(), 5); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("To(5) error = %v, want ErrUnknownVersion", err)
+	}
+	if err := m.Down(context.Background(), 0); err == nil {
+		t.Error("Down(0) succeeded")
+	}
+}
+
+func TestIdent(t *testing.T) {
+	tests := []struct{ table, want string }{
+		{"schema_migrations", `"schema_migrations"`},
+		{"app.schema_migrations", `"app"."schema_migrations"`},
+		{"App.Migrations", `"App"."Migrations"`},
+	}
+	for _, tt := range tests {
+		m := &Migrator{table: tt.table}
+		if got := m.ident(); got != tt.want {
+			t.Errorf("ident(%q) = %s, want %s", tt.table, got, tt.want)
+		}
+	}
+}
+
+func itoa(v int64) string { return strconv.FormatInt(v, 10) }
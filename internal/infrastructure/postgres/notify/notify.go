@@ -0,0 +1,295 @@
+// Package notify bridges PostgreSQL LISTEN/NOTIFY to in-process subscribers.
+//
+// A Listener holds one dedicated connection (LISTEN needs a session, so it cannot borrow
+// pooled connections), LISTENs on a fixed set of channels and fans every notification out
+// to the subscribers of its channel. The connection is reopened with backoff when it drops,
+// and the channels LISTENed again. Notifications sent while it was down are lost: subscribers
+// then receive a Resync notification, to reload what they display.
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrUnknownChannel means the channel is not one the listener LISTENs on.
+	ErrUnknownChannel = errors.New("unknown notification channel")
+	// ErrClosed means the listener stopped accepting subscribers (see Close).
+	ErrClosed = errors.New("notifications closed")
+)
+
+const (
+	// connectTimeout bounds opening the connection and LISTENing.
+	connectTimeout = 10 * time.Second
+	// idlePing is how long the connection may stay silent before it is pinged, so a
+	// connection dropped without a FIN is noticed.
+	idlePing = 30 * time.Second
+	// firstReconnectDelay doubles on every failed attempt, up to Options.MaxReconnectDelay.
+	firstReconnectDelay = time.Second
+)
+
+// Notification is one NOTIFY, or a Resync after the connection was reopened.
+type Notification struct {
+	Channel string    `json:"channel"`
+	Payload string    `json:"payload,omitempty"`
+	PID     uint32    `json:"pid,omitempty"` // of the notifying backend
+	At      time.Time `json:"at"`            // when it was received
+	// Resync means notifications may have been missed while the connection was down.
+	Resync bool `json:"resync,omitempty"`
+}
+
+// Options tunes a Listener.
+type Options struct {
+	Buffer            int           // notifications queued per subscriber
+	MaxReconnectDelay time.Duration // reconnection backoff ceiling
+}
+
+// Status describes the listener for readiness and metrics.
+type Status struct {
+	Connected          bool           `json:"connected"`
+	Since              time.Time      `json:"since"` // of the current state
+	Error              string         `json:"error,omitempty"`
+	Channels           []string       `json:"channels"`
+	Subscribers        map[string]int `json:"subscribers"` // by channel
+	Reconnects         int64          `json:"reconnects"`
+	Received           int64          `json:"received"`
+	DroppedSubscribers int64          `json:"droppedSubscribers"` // too slow to keep up
+}
+
+// Listener LISTENs on channels over a dedicated connection and fans notifications out.
+type Listener struct {
+	cfg      *pgx.ConnConfig
+	channels []string
+	opts     Options
+
+	mu        sync.Mutex
+	subs      map[string]map[*Subscription]struct{}
+	closed    bool
+	connected bool
+	since     time.Time
+	lastErr   error
+	sessions  int64 // successful connections, the first one included
+	received  int64
+	dropped   int64
+}
+
+// New returns a listener connecting with cfg; nothing happens before Run.
+func New(cfg *pgx.ConnConfig, channels []string, opts Options) *Listener {
+	l := &Listener{
+		cfg:      cfg,
+		channels: slices.Clone(channels),
+		opts:     opts,
+		subs:     map[string]map[*Subscription]struct{}{},
+		since:    time.Now(),
+		lastErr:  errors.New("not connected yet"),
+	}
+	for _, ch := range channels {
+		l.subs[ch] = map[*Subscription]struct{}{}
+	}
+	return l
+}
+
+// HeartbeatInterval is the longest Run goes without calling beat, reconnection backoff included.
+func (l *Listener) HeartbeatInterval() time.Duration {
+	return connectTimeout + idlePing + l.opts.MaxReconnectDelay
+}
+
+// Run keeps the connection up until ctx is canceled, calling beat at least every
+// HeartbeatInterval.
+func (l *Listener) Run(ctx context.Context, beat func()) {
+	delay := firstReconnectDelay
+	for {
+		beat()
+		connected, err := l.session(ctx, beat)
+		if ctx.Err() != nil {
+			l.setState(false, ctx.Err())
+			return
+		}
+		if connected {
+			delay = firstReconnectDelay
+		}
+		l.setState(false, err)
+		slog.Warn("notification listener disconnected", "error", err, "retryIn", delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, l.opts.MaxReconnectDelay)
+	}
+}
+
+// session connects, LISTENs and delivers notifications until the connection fails.
+func (l *Listener) session(ctx context.Context, beat func()) (connected bool, err error) {
+	cctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	conn, err := pgx.ConnectConfig(cctx, l.cfg)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+	for _, ch := range l.channels {
+		if _, err := conn.Exec(cctx, "LISTEN "+pgx.Identifier{ch}.Sanitize()); err != nil {
+			return false, err
+		}
+	}
+	l.setState(true, nil)
+	slog.Info("notification listener connected", "channels", l.channels)
+
+	for {
+		beat()
+		wctx, cancel := context.WithTimeout(ctx, idlePing)
+		n, err := conn.WaitForNotification(wctx)
+		cancel()
+		switch {
+		case err == nil:
+			l.dispatch(Notification{Channel: n.Channel, Payload: n.Payload, PID: n.PID, At: time.Now().UTC()})
+		case ctx.Err() != nil:
+			return true, ctx.Err()
+		case pgconn.Timeout(err):
+			pctx, cancel := context.WithTimeout(ctx, connectTimeout)
+			err = conn.Ping(pctx)
+			cancel()
+			if err != nil {
+				return true, err
+			}
+		default:
+			return true, err
+		}
+	}
+}
+
+// setState records a connection change; reconnecting tells the subscribers to resync.
+func (l *Listener) setState(connected bool, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.connected != connected {
+		l.since = time.Now()
+	}
+	l.connected, l.lastErr = connected, err
+	if !connected {
+		return
+	}
+	l.sessions++
+	if l.sessions > 1 {
+		for _, ch := range l.channels {
+			l.deliverLocked(Notification{Channel: ch, At: time.Now().UTC(), Resync: true})
+		}
+	}
+}
+
+func (l *Listener) dispatch(n Notification) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.received++
+	l.deliverLocked(n)
+}
+
+// deliverLocked queues n for every subscriber of its channel. A subscriber whose queue is
+// full is dropped instead of slowing everyone down: its channel is closed. l.mu is held.
+func (l *Listener) deliverLocked(n Notification) {
+	for s := range l.subs[n.Channel] {
+		select {
+		case s.c <- n:
+		default:
+			s.dropped = true
+			l.dropped++
+			l.removeLocked(s)
+		}
+	}
+}
+
+// Subscribe returns a subscription to channel's notifications; Close it when done.
+func (l *Listener) Subscribe(channel string) (*Subscription, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	subs, ok := l.subs[channel]
+	if !ok {
+		return nil, ErrUnknownChannel
+	}
+	if l.closed {
+		return nil, ErrClosed
+	}
+	s := &Subscription{l: l, channel: channel, c: make(chan Notification, max(l.opts.Buffer, 1))}
+	subs[s] = struct{}{}
+	return s, nil
+}
+
+// Close ends every subscription and refuses new ones, so streams do not hold up a shutdown.
+func (l *Listener) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	for _, subs := range l.subs {
+		for s := range subs {
+			l.removeLocked(s)
+		}
+	}
+}
+
+func (l *Listener) removeLocked(s *Subscription) {
+	if _, ok := l.subs[s.channel][s]; ok {
+		delete(l.subs[s.channel], s)
+		close(s.c)
+	}
+}
+
+// Status returns a snapshot of the connection state and counters.
+func (l *Listener) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st := Status{
+		Connected:          l.connected,
+		Since:              l.since.UTC(),
+		Channels:           l.channels,
+		Subscribers:        make(map[string]int, len(l.subs)),
+		Reconnects:         max(l.sessions-1, 0),
+		Received:           l.received,
+		DroppedSubscribers: l.dropped,
+	}
+	if !l.connected && l.lastErr != nil {
+		st.Error = l.lastErr.Error()
+	}
+	for ch, subs := range l.subs {
+		st.Subscribers[ch] = len(subs)
+	}
+	return st
+}
+
+// Subscription receives the notifications of one channel.
+type Subscription struct {
+	l       *Listener
+	channel string
+	c       chan Notification
+	dropped bool // guarded by l.mu
+}
+
+// C delivers the notifications. It is closed when the subscription ends: Close, Listener.Close,
+// or the subscriber falling behind (see Dropped).
+func (s *Subscription) C() <-chan Notification { return s.c }
+
+// Dropped reports whether C was closed because the subscriber did not keep up.
+func (s *Subscription) Dropped() bool {
+	s.l.mu.Lock()
+	defer s.l.mu.Unlock()
+	return s.dropped
+}
+
+// Close ends the subscription; it is safe to call more than once.
+func (s *Subscription) Close() {
+	s.l.mu.Lock()
+	defer s.l.mu.Unlock()
+	s.l.removeLocked(s)
+}
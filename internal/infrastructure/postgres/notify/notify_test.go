@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+)
+
+// drain reads what is queued on s without blocking, and whether C is closed.
+func drain(s *Subscription) (got []Notification, closed bool) {
+	for {
+		select {
+		case n, ok := <-s.C():
+			if !ok {
+				return got, true
+			}
+			got = append(got, n)
+		default:
+			return got, false
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		buffer      int
+		sent        int // notifications dispatched on "a" before reading
+		wantQueued  int
+		wantDropped bool
+	}{
+		{name: "within the buffer", buffer: 4, sent: 3, wantQueued: 3},
+		{name: "fills the buffer", buffer: 4, sent: 4, wantQueued: 4},
+		{name: "overflows the buffer", buffer: 4, sent: 5, wantQueued: 4, wantDropped: true},
+		{name: "buffer below 1 holds one", buffer: 0, sent: 2, wantQueued: 1, wantDropped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(nil, []string{"a", "b"}, Options{Buffer: tt.buffer})
+			s, err := l.Subscribe("a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			other, _ := l.Subscribe("b")
+			for range tt.sent {
+				l.dispatch(Notification{Channel: "a", Payload: "x"})
+			}
+
+			got, closed := drain(s)
+			if len(got) != tt.wantQueued || closed != tt.wantDropped || s.Dropped() != tt.wantDropped {
+				t.Errorf("queued %d, closed %v, Dropped() %v; want %d, %v, %v",
+					len(got), closed, s.Dropped(), tt.wantQueued, tt.wantDropped, tt.wantDropped)
+			}
+			if got, _ := drain(other); len(got) != 0 {
+				t.Errorf("the subscriber of b got %d notifications of a", len(got))
+			}
+
+			wantSubscribers, wantDropped := 1, int64(0)
+			if tt.wantDropped {
+				wantSubscribers, wantDropped = 0, 1
+			}
+			st := l.Status()
+			if st.Received != int64(tt.sent) || st.DroppedSubscribers != wantDropped || st.Subscribers["a"] != wantSubscribers {
+				t.Errorf("Status() = %+v, want %d received, %d dropped, %d subscribers of a",
+					st, tt.sent, wantDropped, wantSubscribers)
+			}
+		})
+	}
+}
+
+func TestSlowSubscriberDoesNotAffectOthers(t *testing.T) {
+	l := New(nil, []string{"a"}, Options{Buffer: 2})
+	fast, _ := l.Subscribe("a")
+	slow, _ := l.Subscribe("a")
+	for range 5 {
+		l.dispatch(Notification{Channel: "a"})
+		if got, closed := drain(fast); len(got) != 1 || closed {
+			t.Fatalf("fast subscriber got %d notifications, closed %v", len(got), closed)
+		}
+	}
+	if _, closed := drain(slow); !closed || !slow.Dropped() {
+		t.Error("slow subscriber was not dropped")
+	}
+	if fast.Dropped() {
+		t.Error("fast subscriber was dropped")
+	}
+}
+
+func TestResyncAfterReconnect(t *testing.T) {
+	l := New(nil, []string{"a", "b"}, Options{Buffer: 4})
+	s, _ := l.Subscribe("b")
+
+	l.setState(true, nil) // first connection: nothing was missed
+	if got, _ := drain(s); len(got) != 0 {
+		t.Fatalf("first connection sent %v", got)
+	}
+	l.setState(false, errors.New("connection reset"))
+	if st := l.Status(); st.Connected || st.Error != "connection reset" {
+		t.Errorf("Status() after a disconnect = %+v", st)
+	}
+	l.setState(true, nil)
+	got, _ := drain(s)
+	if len(got) != 1 || !got[0].Resync || got[0].Channel != "b" {
+		t.Errorf("after a reconnect, got %+v, want one resync of b", got)
+	}
+	if st := l.Status(); !st.Connected || st.Reconnects != 1 || st.Error != "" {
+		t.Errorf("Status() after a reconnect = %+v", st)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	l := New(nil, []string{"a"}, Options{Buffer: 1})
+	if _, err := l.Subscribe("unknown"); !errors.Is(err, ErrUnknownChannel) {
+		t.Errorf("Subscribe(unknown) error = %v, want ErrUnknownChannel", err)
+	}
+	s, err := l.Subscribe("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	s.Close() // idempotent
+	if _, closed := drain(s); !closed || s.Dropped() {
+		t.Error("Close() does not close C, or reports a drop")
+	}
+
+	open, _ := l.Subscribe("a")
+	l.Close()
+	if _, closed := drain(open); !closed {
+		t.Error("Listener.Close() leaves subscriptions open")
+	}
+	open.Close() // after Listener.Close
+	if _, err := l.Subscribe("a"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe() after Close error = %v, want ErrClosed", err)
+	}
+}
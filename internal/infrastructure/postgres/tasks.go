@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/khedhrije/tools-archetype/internal/domain"
+)
+
+// TaskRepository is the example repository: every method goes through DB.Conn so it joins
+// the caller's transaction, and through MapError so callers only see domain errors. Its table
+// is not part of the service schema and its handlers are not routed, see examples/tasks.
+type TaskRepository struct {
+	db *DB
+}
+
+var _ domain.TaskRepository = (*TaskRepository)(nil)
+
+// NewTaskRepository returns a repository of the tasks table.
+func NewTaskRepository(db *DB) *TaskRepository {
+	return &TaskRepository{db: db}
+}
+
+const taskColumns = "id, title, done, created_at, updated_at"
+
+func (r *TaskRepository) List(ctx context.Context) ([]domain.Task, error) {
+	rows, err := r.db.Conn(ctx).Query(ctx, "SELECT "+taskColumns+" FROM tasks ORDER BY id")
+	if err != nil {
+		return nil, MapError(err)
+	}
+	tasks, err := pgx.CollectRows(rows, scanTask)
+	return tasks, MapError(err)
+}
+
+func (r *TaskRepository) Get(ctx context.Context, id int64) (domain.Task, error) {
+	return r.get(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = $1", id)
+}
+
+func (r *TaskRepository) Create(ctx context.Context, title string) (domain.Task, error) {
+	return r.get(ctx, "INSERT INTO tasks (title) VALUES ($1) RETURNING "+taskColumns, title)
+}
+
+// Update locks the row for the duration of the transaction, so concurrent updates apply one after the other.
+func (r *TaskRepository) Update(ctx context.Context, id int64, fn func(*domain.Task) error) (domain.Task, error) {
+	var t domain.Task
+	err := r.db.WithTx(ctx, TxOptions{}, func(ctx context.Context) error {
+		var err error
+		if t, err = r.get(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = $1 FOR UPDATE", id); err != nil {
+			return err
+		}
+		if err := fn(&t); err != nil {
+			return err
+		}
+		t, err = r.get(ctx, "UPDATE tasks SET title = $2, done = $3, updated_at = now() WHERE id = $1 RETURNING "+taskColumns,
+			id, t.Title, t.Done)
+		return err
+	})
+	return t, err
+}
+
+func (r *TaskRepository) Delete(ctx context.Context, id int64) error {
+	tag, err := r.db.Conn(ctx).Exec(ctx, "DELETE FROM tasks WHERE id = $1", id)
+	if err != nil {
+		return MapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// get runs a statement returning one task.
+func (r *TaskRepository) get(ctx context.Context, sql string, args ...any) (domain.Task, error) {
+	rows, err := r.db.Conn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return domain.Task{}, MapError(err)
+	}
+	t, err := pgx.CollectExactlyOneRow(rows, scanTask)
+	return t, MapError(err)
+}
+
+func scanTask(row pgx.CollectableRow) (domain.Task, error) {
+	var t domain.Task
+	err := row.Scan(&t.ID, &t.Title, &t.Done, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+)
+
+// tlsVersions maps db.tls.min_version to crypto/tls versions.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsParams are the libpq parameters for the db.tls files: pgx then loads them and
+// verifies the server according to the sslmode, as it would from a DSN.
+func tlsParams(t configuration.DatabaseTLSConfig) map[string]string {
+	params := map[string]string{}
+	if t.CAFile != "" {
+		params["sslrootcert"] = t.CAFile
+	}
+	if t.CertFile != "" {
+		params["sslcert"] = t.CertFile
+		params["sslkey"] = t.KeyFile
+	}
+	return params
+}
+
+// withParams sets params on a URL or a keyword/value connection string; they take precedence
+// over the ones already there.
+func withParams(dsn string, params map[string]string) (string, error) {
+	if len(params) == 0 {
+		return dsn, nil
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", errors.New("db.dsn is not a valid URL") // url errors echo the DSN, password included
+		}
+		q := u.Query()
+		for k, v := range params {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+
+	// Later keywords win; values are quoted with ' and \ escaped.
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	var b strings.Builder
+	b.WriteString(dsn)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s='%s'", k, quote.Replace(params[k]))
+	}
+	return b.String(), nil
+}
+
+// applyTLS sets what libpq parameters cannot express on every TLS configuration pgx
+// will try (sslmode prefer and allow fall back from one to the other).
+func applyTLS(cc *pgconn.Config, t configuration.DatabaseTLSConfig) {
+	configs := []*tls.Config{cc.TLSConfig}
+	for _, fb := range cc.Fallbacks {
+		configs = append(configs, fb.TLSConfig)
+	}
+	for _, c := range configs {
+		if c == nil {
+			continue
+		}
+		if v, ok := tlsVersions[t.MinVersion]; ok {
+			c.MinVersion = v
+		}
+		if t.ServerName != "" {
+			c.ServerName = t.ServerName
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/khedhrije/tools-archetype/pkg/requestid"
+)
+
+// latencyBuckets are the upper bounds of the per-statement histograms; a last, unbounded
+// bucket counts the rest.
+var latencyBuckets = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond,
+	50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+const (
+	// maxStatements bounds the histograms kept: statements seen once the limit is reached
+	// (SQL built with literals, ...) are counted together under otherStatements.
+	maxStatements   = 200
+	otherStatements = "(other)"
+	// maxSQLLen truncates the statements kept and logged.
+	maxSQLLen = 500
+)
+
+// SlowQuery is a query that took longer than the tracer's threshold.
+type SlowQuery struct {
+	At         time.Time `json:"at"`
+	SQL        string    `json:"sql"` // arguments are left out: they may hold personal data
+	Duration   string    `json:"duration"`
+	DurationMs float64   `json:"durationMs"`
+	Rows       int64     `json:"rows"` // rows affected
+	Error      string    `json:"error,omitempty"`
+	RequestID  string    `json:"requestId,omitempty"`
+}
+
+// StatementStats is the latency histogram of one statement.
+type StatementStats struct {
+	SQL     string   `json:"sql"`
+	Calls   int64    `json:"calls"`
+	Errors  int64    `json:"errors"`
+	Rows    int64    `json:"rows"` // rows affected, summed
+	TotalMs float64  `json:"totalMs"`
+	MeanMs  float64  `json:"meanMs"`
+	MaxMs   float64  `json:"maxMs"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Bucket counts the calls that took at most LE (cumulative, like Prometheus histograms).
+type Bucket struct {
+	LE    string `json:"le"` // "+Inf" for the last one
+	Count int64  `json:"count"`
+}
+
+// Tracer is a pgx.QueryTracer for the shared pool: it records each query's duration, rows
+// affected and error into per-statement histograms, and logs and keeps the last slow queries
+// with the ID of the request that ran them.
+type Tracer struct {
+	slowThreshold atomic.Int64 // nanoseconds, 0 disables
+
+	mu    sync.Mutex
+	stmts map[string]*statementStats
+	slow  []SlowQuery // ring buffer
+	next  int
+	full  bool
+}
+
+type statementStats struct {
+	calls, errors, rows int64
+	total, max          time.Duration
+	buckets             []int64 // len(latencyBuckets)+1, not cumulative
+}
+
+// NewTracer returns a tracer logging queries slower than slowThreshold and keeping the last keep of them.
+func NewTracer(slowThreshold time.Duration, keep int) *Tracer {
+	t := &Tracer{stmts: map[string]*statementStats{}, slow: make([]SlowQuery, max(keep, 1))}
+	t.SetSlowThreshold(slowThreshold)
+	return t
+}
+
+// SetSlowThreshold changes the threshold of the slow query log, e.g. after a configuration reload.
+func (t *Tracer) SetSlowThreshold(d time.Duration) {
+	t.slowThreshold.Store(int64(d))
+}
+
+// SlowThreshold returns the threshold of the slow query log.
+func (t *Tracer) SlowThreshold() time.Duration {
+	return time.Duration(t.slowThreshold.Load())
+}
+
+type traceKey struct{}
+
+type traceStart struct {
+	at  time.Time
+	sql string
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (t *Tracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, traceStart{at: time.Now(), sql: data.SQL})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (t *Tracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(traceKey{}).(traceStart)
+	if !ok {
+		return
+	}
+	d := time.Since(start.at)
+	sql := normalizeSQL(start.sql)
+	rows := data.CommandTag.RowsAffected()
+
+	t.mu.Lock()
+	t.record(sql, d, rows, data.Err)
+	t.mu.Unlock()
+
+	if th := t.SlowThreshold(); th > 0 && d >= th {
+		q := SlowQuery{
+			At:         start.at.UTC(),
+			SQL:        sql,
+			Duration:   d.String(),
+			DurationMs: ms(d),
+			Rows:       rows,
+			RequestID:  requestid.FromContext(ctx),
+		}
+		if data.Err != nil {
+			q.Error = data.Err.Error()
+		}
+		t.mu.Lock()
+		t.slow[t.next] = q
+		t.next = (t.next + 1) % len(t.slow)
+		t.full = t.full || t.next == 0
+		t.mu.Unlock()
+		attrs := []any{"duration", d, "rows", rows, "sql", sql, "request_id", q.RequestID}
+		if data.Err != nil {
+			attrs = append(attrs, "error", data.Err)
+		}
+		slog.Warn("slow query", attrs...)
+	}
+}
+
+// record adds one call to the statement's histogram; t.mu is held.
+func (t *Tracer) record(sql string, d time.Duration, rows int64, err error) {
+	s, ok := t.stmts[sql]
+	if !ok {
+		if len(t.stmts) >= maxStatements {
+			sql = otherStatements
+		}
+		if s, ok = t.stmts[sql]; !ok {
+			s = &statementStats{buckets: make([]int64, len(latencyBuckets)+1)}
+			t.stmts[sql] = s
+		}
+	}
+	s.calls++
+	s.rows += rows
+	if err != nil {
+		s.errors++
+	}
+	s.total += d
+	s.max = max(s.max, d)
+	i, _ := slices.BinarySearch(latencyBuckets, d)
+	s.buckets[i]++
+}
+
+// SlowQueries returns the slow queries kept, most recent first.
+func (t *Tracer) SlowQueries() []SlowQuery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := t.next
+	if t.full {
+		n = len(t.slow)
+	}
+	out := make([]SlowQuery, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, t.slow[(t.next-i+len(t.slow))%len(t.slow)])
+	}
+	return out
+}
+
+// Statements returns the histograms of the statements run so far, by total time spent, highest first.
+func (t *Tracer) Statements() []StatementStats {
+	t.mu.Lock()
+	out := make([]StatementStats, 0, len(t.stmts))
+	for sql, s := range t.stmts {
+		st := StatementStats{
+			SQL:     sql,
+			Calls:   s.calls,
+			Errors:  s.errors,
+			Rows:    s.rows,
+			TotalMs: ms(s.total),
+			MeanMs:  ms(s.total / time.Duration(s.calls)),
+			MaxMs:   ms(s.max),
+			Buckets: make([]Bucket, len(s.buckets)),
+		}
+		var cum int64
+		for i, n := range s.buckets {
+			cum += n
+			le := "+Inf"
+			if i < len(latencyBuckets) {
+				le = latencyBuckets[i].String()
+			}
+			st.Buckets[i] = Bucket{LE: le, Count: cum}
+		}
+		out = append(out, st)
+	}
+	t.mu.Unlock()
+
+	slices.SortFunc(out, func(a, b StatementStats) int {
+		return cmp.Or(cmp.Compare(b.TotalMs, a.TotalMs), strings.Compare(a.SQL, b.SQL))
+	})
+	return out
+}
+
+// normalizeSQL collapses whitespace so the same statement written over several lines is
+// counted once, and truncates it.
+func normalizeSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > maxSQLLen {
+		sql = strings.ToValidUTF8(sql[:maxSQLLen], "") + "…"
+	}
+	return sql
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/khedhrije/tools-archetype/pkg/requestid"
+)
+
+// traceQuery runs one query through the tracer, made to look like it took d.
+func traceQuery(ctx context.Context, tr *Tracer, sql string, d time.Duration, err error) {
+	ctx = tr.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: sql})
+	start := ctx.Value(traceKey{}).(traceStart)
+	start.at = start.at.Add(-d)
+	ctx = context.WithValue(ctx, traceKey{}, start)
+	tr.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 2"), Err: err})
+}
+
+func TestTracerSlowQueries(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		keep      int
+		durations []time.Duration // of the queries "q0", "q1", ...
+		want      []string        // SQL of SlowQueries(), most recent first
+	}{
+		{
+			name:      "below the threshold",
+			threshold: time.Second,
+			keep:      3,
+			durations: []time.Duration{time.Millisecond, 10 * time.Millisecond},
+			want:      []string{},
+		},
+		{
+			name:      "partially filled",
+			threshold: 100 * time.Millisecond,
+			keep:      3,
+			durations: []time.Duration{200 * time.Millisecond, time.Millisecond, 300 * time.Millisecond},
+			want:      []string{"q2", "q0"},
+		},
+		{
+			name:      "exactly full",
+			threshold: 100 * time.Millisecond,
+			keep:      3,
+			durations: []time.Duration{time.Second, time.Second, time.Second},
+			want:      []string{"q2", "q1", "q0"},
+		},
+		{
+			name:      "wrapped around keeps the newest",
+			threshold: 100 * time.Millisecond,
+			keep:      3,
+			durations: []time.Duration{time.Second, time.Second, time.Second, time.Second, time.Second},
+			want:      []string{"q4", "q3", "q2"},
+		},
+		{
+			name:      "threshold 0 disables the log",
+			threshold: 0,
+			keep:      3,
+			durations: []time.Duration{time.Hour},
+			want:      []string{},
+		},
+		{
+			name:      "keep below 1 keeps one",
+			threshold: time.Millisecond,
+			keep:      0,
+			durations: []time.Duration{time.Second, time.Second},
+			want:      []string{"q1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTracer(tt.threshold, tt.keep)
+			for i, d := range tt.durations {
+				traceQuery(context.Background(), tr, fmt.Sprintf("q%d", i), d, nil)
+			}
+			got := []string{}
+			for _, q := range tr.SlowQueries() {
+				got = append(got, q.SQL)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SlowQueries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTracerSlowQueryDetail(t *testing.T) {
+	tr := NewTracer(10*time.Millisecond, 5)
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	traceQuery(ctx, tr, "UPDATE   t\n  SET x = $1", 50*time.Millisecond, errors.New("boom"))
+
+	slow := tr.SlowQueries()
+	if len(slow) != 1 {
+		t.Fatalf("SlowQueries() = %+v, want one query", slow)
+	}
+	q := slow[0]
+	if q.SQL != "UPDATE t SET x = $1" || q.RequestID != "req-1" || q.Error != "boom" || q.Rows != 2 || q.DurationMs < 50 {
+		t.Errorf("SlowQueries()[0] = %+v", q)
+	}
+
+	tr.SetSlowThreshold(time.Second)
+	traceQuery(ctx, tr, "SELECT 1", 50*time.Millisecond, nil)
+	if n := len(tr.SlowQueries()); n != 1 {
+		t.Errorf("after raising the threshold, %d slow queries, want 1", n)
+	}
+}
+
+func TestTracerStatements(t *testing.T) {
+	tr := NewTracer(0, 1)
+	traceQuery(context.Background(), tr, "SELECT 1", 3*time.Millisecond, nil)
+	traceQuery(context.Background(), tr, "SELECT  1", 20*time.Millisecond, errors.New("boom"))
+	traceQuery(context.Background(), tr, "SELECT 2", 2*time.Second, nil)
+
+	stmts := tr.Statements()
+	if len(stmts) != 2 || stmts[0].SQL != "SELECT 2" || stmts[1].SQL != "SELECT 1" {
+		t.Fatalf("Statements() = %+v, want SELECT 2 then SELECT 1", stmts)
+	}
+	s := stmts[1]
+	if s.Calls != 2 || s.Errors != 1 || s.Rows != 4 {
+		t.Errorf("SELECT 1: calls %d, errors %d, rows %d, want 2, 1, 4", s.Calls, s.Errors, s.Rows)
+	}
+	counts := map[string]int64{}
+	for _, b := range s.Buckets {
+		counts[b.LE] = b.Count
+	}
+	// cumulative: 3ms falls in le=5ms, 20ms in le=25ms
+	for le, want := range map[string]int64{"1ms": 0, "5ms": 1, "10ms": 1, "25ms": 2, "+Inf": 2} {
+		if counts[le] != want {
+			t.Errorf("SELECT 1 bucket le=%s = %d, want %d", le, counts[le], want)
+		}
+	}
+}
+
+func TestNormalizeSQL(t *testing.T) {
+	long := make([]byte, maxSQLLen+10)
+	for i := range long {
+		long[i] = 'x'
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"  SELECT\n\t*\n  FROM t  ", "SELECT * FROM t"},
+		{string(long), string(long[:maxSQLLen]) + "…"},
+	}
+	for _, tt := range tests {
+		if got := normalizeSQL(tt.in); got != tt.want {
+			t.Errorf("normalizeSQL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
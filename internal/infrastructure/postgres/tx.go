@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultTxAttempts is how many times WithTx runs a transaction that keeps failing on a
+// serialization failure or a deadlock, when TxOptions.MaxAttempts is 0.
+const DefaultTxAttempts = 3
+
+// Querier runs statements; both the pool and a transaction implement it.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// TxOptions configures a transaction started by WithTx.
+type TxOptions struct {
+	Isolation   pgx.TxIsoLevel // empty keeps the server's default (read committed)
+	ReadOnly    bool
+	MaxAttempts int // 0 means DefaultTxAttempts, 1 disables retries
+}
+
+// DB is what repositories are built on: Conn runs their statements in the transaction the
+// context carries, so they join a unit of work started by WithTx without knowing about it.
+type DB struct {
+	pool pool
+}
+
+// pool is what DB uses of *pgxpool.Pool.
+type pool interface {
+	Querier
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+}
+
+// NewDB wraps the shared pool.
+func NewDB(pool *pgxpool.Pool) *DB {
+	return &DB{pool: pool}
+}
+
+// txKey keys the transaction of a DB in a context.
+type txKey struct{ db *DB }
+
+// Conn returns the transaction started by WithTx on ctx, or the pool outside one.
+func (db *DB) Conn(ctx context.Context) Querier {
+	if tx, ok := ctx.Value(txKey{db}).(pgx.Tx); ok {
+		return tx
+	}
+	return db.pool
+}
+
+// InTx reports whether ctx carries a transaction of db.
+func (db *DB) InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{db}).(pgx.Tx)
+	return ok
+}
+
+// WithTx runs fn in a transaction, committed when fn returns nil and rolled back otherwise
+// (panics included). fn must run its statements through Conn(ctx) with the ctx it receives.
+//
+// Called within a transaction, WithTx joins it: fn runs in the outer transaction, opts are
+// ignored and the outermost call decides to commit. Otherwise a transaction failing on a
+// serialization failure or a deadlock is retried as a whole, with a short jittered backoff,
+// so fn must have no side effects outside the database.
+func (db *DB) WithTx(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error {
+	if db.InTx(ctx) {
+		return fn(ctx)
+	}
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultTxAttempts
+	}
+	txOpts := pgx.TxOptions{IsoLevel: opts.Isolation}
+	if opts.ReadOnly {
+		txOpts.AccessMode = pgx.ReadOnly
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := pgx.BeginTxFunc(ctx, db.pool, txOpts, func(tx pgx.Tx) error {
+			return fn(context.WithValue(ctx, txKey{db}, tx))
+		})
+		if err == nil || attempt >= attempts || !retryable(err) {
+			return err
+		}
+		backoff := time.Duration(attempt) * 10 * time.Millisecond
+		backoff += rand.N(backoff) // jitter, so the conflicting transactions do not collide again
+		slog.DebugContext(ctx, "retrying transaction", "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// retryable reports a serialization failure or a deadlock: running the transaction again may succeed.
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == codeSerializationFailure || pgErr.Code == codeDeadlockDetected
+}
@@ -0,0 +1,249 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeTx records how the transaction ended.
+type fakeTx struct {
+	pgx.Tx
+	committed, rolledBack bool
+	commitErr             error
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	if tx.committed || tx.rolledBack {
+		return pgx.ErrTxClosed
+	}
+	tx.committed = true
+	return tx.commitErr
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	if tx.committed || tx.rolledBack {
+		return pgx.ErrTxClosed
+	}
+	tx.rolledBack = true
+	return nil
+}
+
+// fakePool begins fakeTx transactions and keeps them with their options.
+type fakePool struct {
+	Querier
+	txs      []*fakeTx
+	opts     []pgx.TxOptions
+	beginErr error
+}
+
+func (p *fakePool) BeginTx(_ context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	if p.beginErr != nil {
+		return nil, p.beginErr
+	}
+	tx := &fakeTx{}
+	p.txs = append(p.txs, tx)
+	p.opts = append(p.opts, opts)
+	return tx, nil
+}
+
+func pgError(code string) error { return &pgconn.PgError{Code: code} }
+
+func TestWithTx(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name         string
+		fn           func(ctx context.Context) error
+		wantErr      error
+		wantCommit   bool
+		wantRollback bool
+	}{
+		{name: "commits", fn: func(context.Context) error { return nil }, wantCommit: true},
+		{name: "rolls back on error", fn: func(context.Context) error { return boom }, wantErr: boom, wantRollback: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakePool{}
+			db := &DB{pool: p}
+			var inside Querier
+			err := db.WithTx(context.Background(), TxOptions{}, func(ctx context.Context) error {
+				if !db.InTx(ctx) {
+					t.Error("InTx() is false within WithTx")
+				}
+				inside = db.Conn(ctx)
+				return tt.fn(ctx)
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("WithTx() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(p.txs) != 1 {
+				t.Fatalf("%d transactions, want 1", len(p.txs))
+			}
+			tx := p.txs[0]
+			if tx.committed != tt.wantCommit || tx.rolledBack != tt.wantRollback {
+				t.Errorf("committed %v, rolled back %v", tx.committed, tx.rolledBack)
+			}
+			if inside != pgx.Tx(tx) {
+				t.Error("Conn() within WithTx is not the transaction")
+			}
+			if db.Conn(context.Background()) != Querier(p) || db.InTx(context.Background()) {
+				t.Error("outside WithTx, Conn() is not the pool")
+			}
+		})
+	}
+}
+
+func TestWithTxPanic(t *testing.T) {
+	p := &fakePool{}
+	db := &DB{pool: p}
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("recovered %v, want the panic of fn", r)
+		}
+		if tx := p.txs[0]; tx.committed || !tx.rolledBack {
+			t.Errorf("after a panic: committed %v, rolled back %v", tx.committed, tx.rolledBack)
+		}
+	}()
+	_ = db.WithTx(context.Background(), TxOptions{}, func(context.Context) error { panic("boom") })
+}
+
+func TestWithTxJoinsOuterTransaction(t *testing.T) {
+	p := &fakePool{}
+	db := &DB{pool: p}
+	other := &DB{pool: &fakePool{}}
+	inner := errors.New("inner")
+
+	err := db.WithTx(context.Background(), TxOptions{}, func(ctx context.Context) error {
+		outer := db.Conn(ctx)
+		err := db.WithTx(ctx, TxOptions{ReadOnly: true, MaxAttempts: 5}, func(ctx context.Context) error {
+			if db.Conn(ctx) != outer {
+				t.Error("the nested call does not run in the outer transaction")
+			}
+			return inner
+		})
+		if !errors.Is(err, inner) {
+			t.Errorf("nested WithTx() error = %v, want the error of its fn", err)
+		}
+		// Another DB does not join: its transactions are its own.
+		if other.InTx(ctx) {
+			t.Error("the transaction of db is seen by another DB")
+		}
+		return nil // the outermost call decides
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.txs) != 1 || !p.txs[0].committed {
+		t.Errorf("%d transactions, want one committed", len(p.txs))
+	}
+	if p.opts[0].AccessMode == pgx.ReadOnly {
+		t.Error("the nested options were applied")
+	}
+}
+
+func TestWithTxOptions(t *testing.T) {
+	p := &fakePool{}
+	db := &DB{pool: p}
+	err := db.WithTx(context.Background(), TxOptions{Isolation: pgx.Serializable, ReadOnly: true}, func(context.Context) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.opts[0]; got.IsoLevel != pgx.Serializable || got.AccessMode != pgx.ReadOnly {
+		t.Errorf("BeginTx options = %+v", got)
+	}
+
+	beginErr := errors.New("pool closed")
+	p = &fakePool{beginErr: beginErr}
+	db = &DB{pool: p}
+	if err := db.WithTx(context.Background(), TxOptions{}, func(context.Context) error { return nil }); !errors.Is(err, beginErr) {
+		t.Errorf("WithTx() error = %v, want the BeginTx error", err)
+	}
+}
+
+func TestWithTxRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		errs        []error // returned by fn on each attempt, then nil
+		wantRuns    int
+		wantErr     string // SQLSTATE of the returned error, "" for success
+	}{
+		{name: "serialization failure then success", errs: []error{pgError(codeSerializationFailure)}, wantRuns: 2},
+		{name: "deadlock then success", errs: []error{pgError(codeDeadlockDetected)}, wantRuns: 2},
+		{
+			name:     "gives up after DefaultTxAttempts",
+			errs:     []error{pgError(codeSerializationFailure), pgError(codeSerializationFailure), pgError(codeDeadlockDetected), nil},
+			wantRuns: DefaultTxAttempts,
+			wantErr:  codeDeadlockDetected,
+		},
+		{
+			name:        "MaxAttempts 1 disables retries",
+			maxAttempts: 1,
+			errs:        []error{pgError(codeSerializationFailure)},
+			wantRuns:    1,
+			wantErr:     codeSerializationFailure,
+		},
+		{
+			name:        "MaxAttempts above the default",
+			maxAttempts: 5,
+			errs:        []error{pgError(codeSerializationFailure), pgError(codeSerializationFailure), pgError(codeSerializationFailure)},
+			wantRuns:    4,
+		},
+		{
+			name:     "other errors are not retried",
+			errs:     []error{pgError(codeUniqueViolation)},
+			wantRuns: 1,
+			wantErr:  codeUniqueViolation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakePool{}
+			db := &DB{pool: p}
+			runs := 0
+			err := db.WithTx(context.Background(), TxOptions{MaxAttempts: tt.maxAttempts}, func(context.Context) error {
+				runs++
+				if runs <= len(tt.errs) {
+					return tt.errs[runs-1]
+				}
+				return nil
+			})
+			var pgErr *pgconn.PgError
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("WithTx() error = %v", err)
+			case tt.wantErr != "" && (!errors.As(err, &pgErr) || pgErr.Code != tt.wantErr):
+				t.Errorf("WithTx() error = %v, want SQLSTATE %s", err, tt.wantErr)
+			}
+			if runs != tt.wantRuns || len(p.txs) != tt.wantRuns {
+				t.Errorf("fn ran %d times in %d transactions, want %d", runs, len(p.txs), tt.wantRuns)
+			}
+			for i, tx := range p.txs {
+				if last := i == len(p.txs)-1; tx.committed != (last && tt.wantErr == "") {
+					t.Errorf("transaction %d committed: %v", i, tx.committed)
+				}
+			}
+		})
+	}
+}
+
+func TestWithTxRetryStopsWithContext(t *testing.T) {
+	db := &DB{pool: &fakePool{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	err := db.WithTx(ctx, TxOptions{MaxAttempts: 10}, func(context.Context) error {
+		runs++
+		cancel()
+		return pgError(codeSerializationFailure)
+	})
+	if runs != 1 || !errors.Is(err, context.Canceled) {
+		t.Errorf("after %d runs, error = %v; want one run and context.Canceled", runs, err)
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		t.Errorf("error = %v, want it to keep the serialization failure", err)
+	}
+}
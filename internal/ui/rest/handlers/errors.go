@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khedhrije/tools-archetype/internal/domain"
+	"github.com/khedhrije/tools-archetype/pkg/requestid"
+)
+
+// writeError answers with the status of a domain error: 404 not found, 409 conflict,
+// 422 invalid. Anything else is logged and answered 500 without details.
+func writeError(c *gin.Context, err error) {
+	var status int
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, domain.ErrInvalid):
+		status = http.StatusUnprocessableEntity
+	default:
+		slog.ErrorContext(c.Request.Context(), "request failed", "path", c.FullPath(), "request_id", requestid.FromContext(c.Request.Context()), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	body := gin.H{"error": err.Error()}
+	var ce *domain.ConstraintError
+	if errors.As(err, &ce) {
+		body["constraint"] = ce.Constraint
+		if ce.Column != "" {
+			body["field"] = ce.Column
+		}
+	}
+	c.JSON(status, body)
+}
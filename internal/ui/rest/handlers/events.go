@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres/notify"
+)
+
+// eventsKeepalive is how often an idle stream gets a comment, so proxies do not time it out.
+const eventsKeepalive = 15 * time.Second
+
+// Events streams the notifications of the channel :channel as server-sent events:
+// "notification" for each NOTIFY, "resync" when some may have been missed after a reconnect,
+// and a last "dropped" one before closing the stream of a client that did not keep up.
+func Events(l *notify.Listener) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sub, err := l.Subscribe(c.Param("channel"))
+		switch {
+		case errors.Is(err, notify.ErrUnknownChannel):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		defer sub.Close()
+
+		// The stream outlives rest.write_timeout.
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no") // nginx would buffer the stream otherwise
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		keepalive := time.NewTicker(eventsKeepalive)
+		defer keepalive.Stop()
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-keepalive.C:
+				_, _ = c.Writer.WriteString(": keepalive\n\n")
+			case n, ok := <-sub.C():
+				if !ok {
+					if sub.Dropped() {
+						c.SSEvent("dropped", gin.H{"channel": c.Param("channel"), "reason": "client too slow"})
+						c.Writer.Flush()
+					}
+					return
+				}
+				event := "notification"
+				if n.Resync {
+					event = "resync"
+				}
+				c.SSEvent(event, n)
+			}
+			c.Writer.Flush()
+		}
+	}
+}
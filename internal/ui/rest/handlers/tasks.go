@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khedhrije/tools-archetype/internal/domain"
+)
+
+// ListTasks answers every task.
+func ListTasks(repo domain.TaskRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tasks, err := repo.List(c.Request.Context())
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, tasks)
+	}
+}
+
+// GetTask answers the task :id.
+func GetTask(repo domain.TaskRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := taskID(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		t, err := repo.Get(c.Request.Context(), id)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+// CreateTask creates a task from {"title": "..."}; an empty title breaks the table's check (422).
+func CreateTask(repo domain.TaskRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Title string `json:"title"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		t, err := repo.Create(c.Request.Context(), body.Title)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, t)
+	}
+}
+
+// UpdateTask changes the title and/or done state of the task :id.
+func UpdateTask(repo domain.TaskRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := taskID(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		var body struct {
+			Title *string `json:"title"`
+			Done  *bool   `json:"done"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		t, err := repo.Update(c.Request.Context(), id, func(t *domain.Task) error {
+			if body.Title != nil {
+				t.Title = *body.Title
+			}
+			if body.Done != nil {
+				t.Done = *body.Done
+			}
+			return nil
+		})
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	}
+}
+
+// DeleteTask deletes the task :id.
+func DeleteTask(repo domain.TaskRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := taskID(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		if err := repo.Delete(c.Request.Context(), id); err != nil {
+			writeError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// taskID parses :id; an id that cannot exist is not found.
+func taskID(c *gin.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("task %q: %w", c.Param("id"), domain.ErrNotFound)
+	}
+	return id, nil
+}
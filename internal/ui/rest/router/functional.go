@@ -3,14 +3,37 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres/notify"
 	"github.com/khedhrije/tools-archetype/internal/ui/rest/handlers"
 )
 
 // RegisterFunctionalRoutes wires "business/functional" API endpoints
 // under the /api group. Keep tech/ops endpoints in technical.go.
-func RegisterFunctionalRoutes(api *gin.RouterGroup) {
+// db is the shared connection pool (nil when the database is not configured): wrap it in
+// postgres.NewDB and hand repositories built on it to the handlers, like the tasks example below.
+// events streams the db.notify.channels, nil when none are configured.
+func RegisterFunctionalRoutes(api *gin.RouterGroup, db *pgxpool.Pool, events *notify.Listener) {
 	// Example functional endpoint (you can add your domain routes here, e.g. /tasks, /users, etc.)
+	// Ship a route dark behind a feature flag with: api.GET("/tasks", featureflag.Require("tasks"), handler)
 	// NOTE: This keeps the original Ping behavior at GET /api/
 	api.GET("/", handlers.Ping())
 
+	if events != nil {
+		// Server-sent events of a PostgreSQL NOTIFY channel, e.g. /api/events/orders
+		api.GET("/events/:channel", handlers.Events(events))
+	}
+
+	// The tasks example (postgres.TaskRepository, handlers.*Task, examples/tasks) is not routed: these
+	// routes are public and unauthenticated. Once its migration is adopted, wire it like this:
+	//
+	//	if db != nil {
+	//		tasks := postgres.NewTaskRepository(postgres.NewDB(db))
+	//		taskRoutes := api.Group("/tasks", featureflag.Require("tasks"))
+	//		taskRoutes.GET("", handlers.ListTasks(tasks))
+	//		taskRoutes.POST("", handlers.CreateTask(tasks))
+	//		taskRoutes.GET("/:id", handlers.GetTask(tasks))
+	//		taskRoutes.PATCH("/:id", handlers.UpdateTask(tasks))
+	//		taskRoutes.DELETE("/:id", handlers.DeleteTask(tasks))
+	//	}
 }
@@ -3,7 +3,13 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres/notify"
+	"github.com/khedhrije/tools-archetype/internal/ui/rest/handlers"
+	"github.com/khedhrije/tools-archetype/pkg/clientcert"
+	"github.com/khedhrije/tools-archetype/pkg/featureflag"
 	"github.com/khedhrije/tools-archetype/pkg/monitoring"
+	"github.com/khedhrije/tools-archetype/pkg/requestid"
 )
 
 type Options struct {
@@ -12,25 +18,66 @@ type Options struct {
 }
 
 // CreateRouter builds the Gin engine and delegates route registration
-// to the technical, functional, and frontend registrars.
-func CreateRouter(checksHandler monitoring.Handler, opts ...Options) *gin.Engine {
+// to the technical, functional, and frontend registrars. It serves everything
+// on one port (rest.single_port); see CreatePublicRouter and CreateAdminRouter.
+// db is the shared pool handed to the functional handlers, nil when the database is not configured,
+// and events the LISTEN/NOTIFY listener behind /api/events, nil without db.notify.channels.
+func CreateRouter(checksHandler monitoring.Handler, db *pgxpool.Pool, events *notify.Listener, flags *featureflag.Set, opts ...Options) *gin.Engine {
+	r := newEngine(opts)
+
+	// Group all backend routes under /api
+	api := r.Group("/api")
+	// Feature flags are evaluated per request (featureflag.IsEnabled / featureflag.Require)
+	api.Use(flags.Middleware())
+	// Verified mTLS client identity for handlers (clientcert.FromContext / clientcert.Require)
+	api.Use(clientcert.Middleware())
+
+	// Register endpoint families
+	RegisterTechnicalRoutes(api, checksHandler, flags)
+	RegisterFunctionalRoutes(api, db, events)
+	RegisterFrontendRoutes(r)
+
+	return r
+}
+
+// CreatePublicRouter builds the engine exposed through the ingress: functional routes only.
+func CreatePublicRouter(db *pgxpool.Pool, events *notify.Listener, flags *featureflag.Set, opts ...Options) *gin.Engine {
+	r := newEngine(opts)
+
+	api := r.Group("/api")
+	api.Use(flags.Middleware(), clientcert.Middleware())
+	RegisterFunctionalRoutes(api, db, events)
+
+	return r
+}
+
+// CreateAdminRouter builds the engine of the admin listener: technical routes and the monitoring SPA,
+// plus the notification streams the SPA follows (events, nil without db.notify.channels).
+func CreateAdminRouter(checksHandler monitoring.Handler, events *notify.Listener, flags *featureflag.Set, opts ...Options) *gin.Engine {
+	r := newEngine(opts)
+
+	api := r.Group("/api")
+	api.Use(flags.Middleware())
+	RegisterTechnicalRoutes(api, checksHandler, flags)
+	if events != nil {
+		api.GET("/events/:channel", handlers.Events(events))
+	}
+	RegisterFrontendRoutes(r)
+
+	return r
+}
+
+func newEngine(opts []Options) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
 	r := gin.New()
 	r.Use(gin.Recovery())
+	// X-Request-ID on every response, and in the request context for the logs (requestid.FromContext)
+	r.Use(requestid.Middleware())
 
 	// Apply options (if any)
 	if len(opts) > 0 && len(opts[0].TrustedProxies) > 0 {
 		_ = r.SetTrustedProxies(opts[0].TrustedProxies) // ignore error => falls back to default
 	}
-
-	// Group all backend routes under /api
-	api := r.Group("/api")
-
-	// Register endpoint families
-	RegisterTechnicalRoutes(api, checksHandler)
-	RegisterFunctionalRoutes(api)
-	RegisterFrontendRoutes(r)
-
 	return r
 }
@@ -3,32 +3,50 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/khedhrije/tools-archetype/pkg/featureflag"
 	"github.com/khedhrije/tools-archetype/pkg/monitoring"
 )
 
 // RegisterTechnicalRoutes wires health, diagnostics, and data-management endpoints
 // under the /api group (technical / ops-focused).
-func RegisterTechnicalRoutes(api *gin.RouterGroup, checksHandler monitoring.Handler) {
+func RegisterTechnicalRoutes(api *gin.RouterGroup, checksHandler monitoring.Handler, flags *featureflag.Set) {
 	// Basic health/info
 	api.GET("/livez", checksHandler.Livez())
 	api.GET("/readyz", checksHandler.Readyz())
+	api.GET("/startupz", checksHandler.Startupz())
 	api.GET("/healthz", checksHandler.Healthz())
 	api.GET("/version", checksHandler.Version())
 
 	// Server information
 	api.GET("/server", checksHandler.ServerInfo())
 
+	// Effective configuration (secrets masked)
+	api.GET("/config", checksHandler.Config())
+
 	// Check endpoints
 	checks := api.Group("/check")
 	{
 		checks.GET("/database", checksHandler.Check())
+		checks.GET("/database/slow-queries", checksHandler.SlowQueries())
+		checks.GET("/database/:name", checksHandler.CheckDatabase())
+		checks.GET("/migrations", checksHandler.Migrations())
 		checks.GET("/services", checksHandler.Services())
 		checks.GET("/metrics", checksHandler.Metrics())
+		checks.GET("/config", checksHandler.ConfigReload())
+		checks.GET("/components", checksHandler.Components())
 
 		// Alias for fs selftest under /check for consistency
 		checks.POST("/fs/selftest", checksHandler.FilesystemSelfTest())
 	}
 
+	// Feature flags: list, runtime override, back to configured behaviour
+	featureFlags := api.Group("/flags")
+	{
+		featureFlags.GET("", flags.ListHandler())
+		featureFlags.PUT("/:name", flags.OverrideHandler())
+		featureFlags.DELETE("/:name", flags.ClearOverrideHandler())
+	}
+
 	// Data / file management
 	data := api.Group("/data")
 	{
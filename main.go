@@ -1,9 +1,11 @@
 package main
 
 import (
-	"github.com/khedhrije/tools-archetype/internal/bootstrap"
+	"os"
+
+	"github.com/khedhrije/tools-archetype/internal/cli"
 )
 
 func main() {
-	bootstrap.InitBootstrap().Run()
+	os.Exit(cli.Run(os.Args[1:]))
 }
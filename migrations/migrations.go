@@ -0,0 +1,30 @@
+// Package migrations embeds the SQL migrations of the service, kept in sql/.
+//
+// Add a change as sql/NNNN_name.up.sql, with NNNN above the last version, and its
+// NNNN_name.down.sql when it can be rolled back. Never edit a migration once it has
+// been applied somewhere: the checksum mismatch blocks the next run.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres/migrate"
+)
+
+// Only sql/ is embedded. Its .gitkeep lets it compile without a migration (sql/*.sql would not);
+// the migrator ignores files not named like one.
+//
+//go:embed all:sql
+var files embed.FS
+
+// New returns a migrator of the embedded migrations, recorded in db.migrations.table.
+func New(pool *pgxpool.Pool) (*migrate.Migrator, error) {
+	dir, err := fs.Sub(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(pool, dir, configuration.Get().DatabaseConfig.Migrations.Table)
+}
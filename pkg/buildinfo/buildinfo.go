@@ -0,0 +1,98 @@
+// Package buildinfo reports what the running binary was built from. Values injected at
+// link time win, then the data the Go toolchain embeds (runtime/debug.ReadBuildInfo):
+//
+//	go build -ldflags "-X github.com/khedhrije/tools-archetype/pkg/buildinfo.Version=1.2.3 \
+//	  -X github.com/khedhrije/tools-archetype/pkg/buildinfo.Revision=$(git rev-parse HEAD) \
+//	  -X github.com/khedhrije/tools-archetype/pkg/buildinfo.BuiltAt=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Injected with -ldflags "-X ...". Empty or "unknown" means not injected.
+var (
+	Version  string
+	Revision string
+	BuiltAt  string
+)
+
+const unknown = "unknown"
+
+// Info describes the binary.
+type Info struct {
+	Version   string       `json:"version"`
+	Revision  string       `json:"revision"`
+	BuiltAt   string       `json:"builtAt"`
+	Dirty     bool         `json:"dirty"` // built from a tree with uncommitted changes
+	Module    string       `json:"module,omitempty"`
+	GoVersion string       `json:"goVersion"`
+	GOOS      string       `json:"goos"`
+	GOARCH    string       `json:"goarch"`
+	Deps      []Dependency `json:"dependencies,omitempty"`
+}
+
+// Dependency is a module linked into the binary.
+type Dependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Sum     string `json:"sum,omitempty"`
+	Replace string `json:"replace,omitempty"`
+}
+
+// Get returns the build information. fallback supplies Version/Revision/BuiltAt
+// (typically from configuration) for values neither ldflags nor the toolchain provide.
+func Get(fallback Info) Info {
+	info := fromBinary()
+	info.Version = first(Version, info.Version, fallback.Version)
+	info.Revision = first(Revision, info.Revision, fallback.Revision)
+	info.BuiltAt = first(BuiltAt, info.BuiltAt, fallback.BuiltAt)
+	return info
+}
+
+// fromBinary reads the embedded build information once; slices are shared, do not modify.
+var fromBinary = sync.OnceValue(func() Info {
+	info := Info{
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Module = bi.Main.Path
+	if bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.BuiltAt = s.Value
+		case "vcs.modified":
+			info.Dirty = s.Value == "true"
+		}
+	}
+	for _, d := range bi.Deps {
+		dep := Dependency{Path: d.Path, Version: d.Version, Sum: d.Sum}
+		if d.Replace != nil {
+			dep.Replace = d.Replace.Path + "@" + d.Replace.Version
+		}
+		info.Deps = append(info.Deps, dep)
+	}
+	return info
+})
+
+// first returns the first value that is set, or "unknown".
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" && v != unknown {
+			return v
+		}
+	}
+	return unknown
+}
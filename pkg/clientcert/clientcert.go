@@ -0,0 +1,62 @@
+// Package clientcert exposes the verified TLS client certificate of a request
+// (mutual TLS) so handlers can authorize on it.
+package clientcert
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"time"
+)
+
+// Identity describes the verified client certificate of a request.
+type Identity struct {
+	Subject      string    `json:"subject"` // RFC 2253, e.g. "CN=billing,O=acme"
+	CommonName   string    `json:"commonName"`
+	Organization []string  `json:"organization,omitempty"`
+	DNSNames     []string  `json:"dnsNames,omitempty"`
+	URIs         []string  `json:"uris,omitempty"` // e.g. SPIFFE IDs
+	Emails       []string  `json:"emails,omitempty"`
+	Issuer       string    `json:"issuer"`
+	Serial       string    `json:"serial"`
+	NotAfter     time.Time `json:"notAfter"`
+}
+
+type ctxKey struct{}
+
+// FromRequest returns the identity of the leaf certificate the TLS stack verified.
+// Certificates presented but not verified (no client CA configured) are ignored.
+func FromRequest(r *http.Request) (Identity, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return Identity{}, false
+	}
+	return identity(r.TLS.VerifiedChains[0][0]), true
+}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the identity stored by Middleware.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(ctxKey{}).(Identity)
+	return id, ok
+}
+
+func identity(cert *x509.Certificate) Identity {
+	id := Identity{
+		Subject:      cert.Subject.String(),
+		CommonName:   cert.Subject.CommonName,
+		Organization: cert.Subject.Organization,
+		DNSNames:     cert.DNSNames,
+		Emails:       cert.EmailAddresses,
+		Issuer:       cert.Issuer.String(),
+		Serial:       cert.SerialNumber.String(),
+		NotAfter:     cert.NotAfter,
+	}
+	for _, u := range cert.URIs {
+		id.URIs = append(id.URIs, u.String())
+	}
+	return id
+}
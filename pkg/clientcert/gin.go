@@ -0,0 +1,36 @@
+package clientcert
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware stores the verified client identity, when there is one, in the request context.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if id, ok := FromRequest(c.Request); ok {
+			c.Request = c.Request.WithContext(NewContext(c.Request.Context(), id))
+		}
+		c.Next()
+	}
+}
+
+// Require answers 403 unless the request carries a verified client certificate
+// whose common name is one of commonNames (any verified certificate when empty):
+//
+//	api.POST("/payouts", clientcert.Require("billing"), handler)
+func Require(commonNames ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := FromContext(c.Request.Context())
+		if !ok {
+			id, ok = FromRequest(c.Request)
+		}
+		if !ok || (len(commonNames) > 0 && !slices.Contains(commonNames, id.CommonName)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "client certificate not authorized"})
+			return
+		}
+		c.Next()
+	}
+}
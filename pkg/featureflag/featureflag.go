@@ -0,0 +1,256 @@
+// Package featureflag evaluates config-driven feature flags (on/off, percentage rollout,
+// tenant and header targeting) with runtime overrides persisted to disk.
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// Definition describes one flag. A request gets the flag when Enabled is true, it matches
+// every targeting rule that is set (Tenants, Headers) and it falls in the Percentage
+// rollout (nil means 100%). An override replaces the whole evaluation.
+type Definition struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Enabled     bool                `json:"enabled"`
+	Percentage  *int                `json:"percentage,omitempty"`
+	Tenants     []string            `json:"tenants,omitempty"`
+	Headers     map[string][]string `json:"headers,omitempty"`
+}
+
+// Request carries the attributes flags are evaluated against.
+type Request struct {
+	Tenant string
+	Header http.Header
+}
+
+// Status is a flag as reported by List.
+type Status struct {
+	Definition
+	Override *bool `json:"override,omitempty"`
+}
+
+// Set holds the flag definitions and the runtime overrides.
+type Set struct {
+	mu           sync.RWMutex
+	defs         map[string]Definition
+	overrides    map[string]bool
+	storePath    string
+	tenantHeader string
+}
+
+type Option func(*Set)
+
+// WithStore persists overrides as JSON at path so they survive restarts.
+func WithStore(path string) Option {
+	return func(s *Set) { s.storePath = path }
+}
+
+// WithTenantHeader sets the request header the tenant is read from (default X-Tenant-ID).
+func WithTenantHeader(header string) Option {
+	return func(s *Set) {
+		if header != "" {
+			s.tenantHeader = header
+		}
+	}
+}
+
+// New builds a Set from defs. Call LoadOverrides to restore persisted overrides.
+func New(defs []Definition, opts ...Option) *Set {
+	s := &Set{overrides: map[string]bool{}, tenantHeader: "X-Tenant-ID"}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.SetDefinitions(defs)
+	return s
+}
+
+// SetDefinitions replaces the definitions, e.g. after a configuration reload.
+// Overrides are kept, including those of flags that no longer exist.
+func (s *Set) SetDefinitions(defs []Definition) {
+	m := make(map[string]Definition, len(defs))
+	for _, d := range defs {
+		d.Name = normalize(d.Name)
+		canonical := make(map[string][]string, len(d.Headers))
+		for h, values := range d.Headers {
+			canonical[http.CanonicalHeaderKey(h)] = values
+		}
+		d.Headers = canonical
+		m[d.Name] = d
+	}
+	s.mu.Lock()
+	s.defs = m
+	s.mu.Unlock()
+}
+
+// Evaluate reports whether name is enabled for req. Unknown flags are disabled.
+func (s *Set) Evaluate(name string, req Request) bool {
+	name = normalize(name)
+	s.mu.RLock()
+	def, ok := s.defs[name]
+	override, overridden := s.overrides[name]
+	s.mu.RUnlock()
+
+	switch {
+	case !ok:
+		return false
+	case overridden:
+		return override
+	case !def.Enabled:
+		return false
+	}
+	if len(def.Tenants) > 0 && !slices.Contains(def.Tenants, req.Tenant) {
+		return false
+	}
+	for h, accepted := range def.Headers {
+		if !slices.Contains(accepted, req.Header.Get(h)) {
+			return false
+		}
+	}
+	if def.Percentage == nil {
+		return true
+	}
+	return bucket(name, req.Tenant) < *def.Percentage
+}
+
+// bucket places a request in [0,100). Requests of the same tenant stick to one bucket,
+// anonymous ones are spread per request.
+func bucket(name, tenant string) int {
+	if tenant == "" {
+		return rand.IntN(100)
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + "\x00" + tenant))
+	return int(h.Sum32() % 100)
+}
+
+// List returns every defined flag with its override, sorted by name.
+func (s *Set) List() []Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Status, 0, len(s.defs))
+	for name, def := range s.defs {
+		st := Status{Definition: def}
+		if v, ok := s.overrides[name]; ok {
+			st.Override = &v
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Override forces name on or off for every request and persists the change.
+func (s *Set) Override(name string, enabled bool) error {
+	name = normalize(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.defs[name]; !ok {
+		return ErrUnknownFlag
+	}
+	s.overrides[name] = enabled
+	return s.save()
+}
+
+// ClearOverride returns name to its configured behaviour and persists the change.
+func (s *Set) ClearOverride(name string) error {
+	name = normalize(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.overrides[name]; !ok {
+		return nil
+	}
+	delete(s.overrides, name)
+	return s.save()
+}
+
+// LoadOverrides restores the overrides saved by a previous run. A missing store is not an error.
+func (s *Set) LoadOverrides() error {
+	if s.storePath == "" {
+		return nil
+	}
+	b, err := os.ReadFile(s.storePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	overrides := map[string]bool{}
+	if err := json.Unmarshal(b, &overrides); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, v := range overrides {
+		s.overrides[normalize(name)] = v
+	}
+	return nil
+}
+
+// save writes the overrides atomically; callers hold s.mu.
+func (s *Set) save() error {
+	if s.storePath == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(s.overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.storePath), ".feature-flags-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.storePath)
+}
+
+// -------------------------
+// Context
+// -------------------------
+
+type ctxKey struct{}
+
+type evaluation struct {
+	set *Set
+	req Request
+}
+
+// NewContext attaches the set and the request attributes to ctx for IsEnabled.
+func NewContext(ctx context.Context, s *Set, req Request) context.Context {
+	return context.WithValue(ctx, ctxKey{}, evaluation{set: s, req: req})
+}
+
+// IsEnabled evaluates name for the request carried by ctx (see Middleware).
+// It returns false when ctx carries no flag set.
+func IsEnabled(ctx context.Context, name string) bool {
+	ev, ok := ctx.Value(ctxKey{}).(evaluation)
+	if !ok || ev.set == nil {
+		return false
+	}
+	return ev.set.Evaluate(name, ev.req)
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
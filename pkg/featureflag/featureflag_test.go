@@ -0,0 +1,171 @@
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func percent(p int) *int { return &p }
+
+func TestEvaluate(t *testing.T) {
+	defs := []Definition{
+		{Name: "on", Enabled: true},
+		{Name: "off", Enabled: false},
+		{Name: "Mixed-Case", Enabled: true},
+		{Name: "tenants", Enabled: true, Tenants: []string{"acme", "globex"}},
+		{Name: "headers", Enabled: true, Headers: map[string][]string{"x-beta": {"1", "yes"}}},
+		{Name: "both", Enabled: true, Tenants: []string{"acme"}, Headers: map[string][]string{"X-Beta": {"1"}}},
+		{Name: "none", Enabled: true, Percentage: percent(0)},
+		{Name: "all", Enabled: true, Percentage: percent(100)},
+		{Name: "disabled-rollout", Enabled: false, Percentage: percent(100)},
+	}
+	beta := http.Header{"X-Beta": {"1"}}
+	tests := []struct {
+		flag string
+		req  Request
+		want bool
+	}{
+		{"on", Request{}, true},
+		{"off", Request{}, false},
+		{"unknown", Request{}, false},
+		{"mixed-case", Request{}, true},
+		{" ON ", Request{}, true},
+		{"tenants", Request{Tenant: "acme"}, true},
+		{"tenants", Request{Tenant: "globex"}, true},
+		{"tenants", Request{Tenant: "initech"}, false},
+		{"tenants", Request{}, false},
+		{"headers", Request{Header: beta}, true},
+		{"headers", Request{Header: http.Header{"X-Beta": {"yes"}}}, true},
+		{"headers", Request{Header: http.Header{"X-Beta": {"0"}}}, false},
+		{"headers", Request{Header: http.Header{}}, false},
+		{"both", Request{Tenant: "acme", Header: beta}, true},
+		{"both", Request{Tenant: "acme", Header: http.Header{}}, false},
+		{"both", Request{Tenant: "globex", Header: beta}, false},
+		{"none", Request{Tenant: "acme"}, false},
+		{"all", Request{Tenant: "acme"}, true},
+		{"all", Request{}, true},
+		{"disabled-rollout", Request{Tenant: "acme"}, false},
+	}
+	s := New(defs)
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%s", tt.flag, tt.req.Tenant), func(t *testing.T) {
+			if got := s.Evaluate(tt.flag, tt.req); got != tt.want {
+				t.Errorf("Evaluate(%q, %+v) = %v, want %v", tt.flag, tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluatePercentageIsStickyPerTenant(t *testing.T) {
+	s := New([]Definition{{Name: "rollout", Enabled: true, Percentage: percent(30)}})
+	enabled := 0
+	const tenants = 2000
+	for i := range tenants {
+		req := Request{Tenant: fmt.Sprintf("tenant-%d", i)}
+		first := s.Evaluate("rollout", req)
+		for range 5 {
+			if s.Evaluate("rollout", req) != first {
+				t.Fatalf("tenant %s changed bucket between requests", req.Tenant)
+			}
+		}
+		if first {
+			enabled++
+		}
+	}
+	// fnv spreads the tenants evenly: allow a few points around 30%
+	if got := enabled * 100 / tenants; got < 25 || got > 35 {
+		t.Errorf("%d%% of the tenants got a 30%% rollout", got)
+	}
+}
+
+func TestBucketDependsOnFlag(t *testing.T) {
+	differ := false
+	for i := range 50 {
+		tenant := fmt.Sprintf("tenant-%d", i)
+		if bucket("a", tenant) != bucket("b", tenant) {
+			differ = true
+		}
+		if b := bucket("a", tenant); b < 0 || b >= 100 {
+			t.Fatalf("bucket(a, %s) = %d, out of [0,100)", tenant, b)
+		}
+	}
+	if !differ {
+		t.Error("a tenant falls in the same bucket for every flag")
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "flags.json")
+	defs := []Definition{{Name: "beta", Enabled: false}, {Name: "legacy", Enabled: true}}
+
+	s := New(defs, WithStore(store))
+	if err := s.LoadOverrides(); err != nil {
+		t.Fatalf("LoadOverrides() without a store: %v", err)
+	}
+	if err := s.Override("BETA", true); err != nil {
+		t.Fatalf("Override(BETA): %v", err)
+	}
+	if err := s.Override("legacy", false); err != nil {
+		t.Fatalf("Override(legacy): %v", err)
+	}
+	if err := s.Override("missing", true); !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("Override(missing) error = %v, want ErrUnknownFlag", err)
+	}
+	if !s.Evaluate("beta", Request{}) || s.Evaluate("legacy", Request{}) {
+		t.Error("overrides are not applied")
+	}
+
+	// A new set, as after a restart, restores them from the store.
+	restarted := New(defs, WithStore(store))
+	if err := restarted.LoadOverrides(); err != nil {
+		t.Fatalf("LoadOverrides(): %v", err)
+	}
+	if !restarted.Evaluate("beta", Request{}) || restarted.Evaluate("legacy", Request{}) {
+		t.Error("overrides are not restored")
+	}
+	for _, st := range restarted.List() {
+		if st.Override == nil {
+			t.Errorf("List(): %s has no override", st.Name)
+		}
+	}
+
+	if err := restarted.ClearOverride("legacy"); err != nil {
+		t.Fatalf("ClearOverride(legacy): %v", err)
+	}
+	again := New(defs, WithStore(store))
+	if err := again.LoadOverrides(); err != nil {
+		t.Fatalf("LoadOverrides(): %v", err)
+	}
+	if !again.Evaluate("legacy", Request{}) {
+		t.Error("a cleared override is still saved")
+	}
+	if !again.Evaluate("beta", Request{}) {
+		t.Error("clearing one override dropped another")
+	}
+}
+
+func TestLoadOverridesCorruptStore(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "flags.json")
+	if err := os.WriteFile(store, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(nil, WithStore(store)).LoadOverrides(); err == nil {
+		t.Error("LoadOverrides() of a corrupt store succeeded")
+	}
+}
+
+func TestIsEnabled(t *testing.T) {
+	s := New([]Definition{{Name: "on", Enabled: true}})
+	if IsEnabled(context.Background(), "on") {
+		t.Error("IsEnabled() without a set in the context is true")
+	}
+	ctx := NewContext(context.Background(), s, Request{})
+	if !IsEnabled(ctx, "on") || IsEnabled(ctx, "off") {
+		t.Error("IsEnabled() does not evaluate the set of the context")
+	}
+}
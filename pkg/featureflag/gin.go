@@ -0,0 +1,74 @@
+package featureflag
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware makes the set available to IsEnabled through the request context.
+func (s *Set) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := Request{Tenant: c.GetHeader(s.tenantHeader), Header: c.Request.Header}
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), s, req))
+		c.Next()
+	}
+}
+
+// Require hides a route (404) while name is disabled for the request. It needs Middleware upstream.
+func Require(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsEnabled(c.Request.Context(), name) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Next()
+	}
+}
+
+// --- technical endpoints ---
+
+// ListHandler serves GET /api/flags.
+func (s *Set) ListHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"flags": s.List()})
+	}
+}
+
+// OverrideHandler serves PUT /api/flags/:name with body {"enabled": true|false}.
+func (s *Set) OverrideHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil || body.Enabled == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": `expected body {"enabled": true|false}`})
+			return
+		}
+		if err := s.Override(c.Param("name"), *body.Enabled); err != nil {
+			s.writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"name": normalize(c.Param("name")), "override": *body.Enabled})
+	}
+}
+
+// ClearOverrideHandler serves DELETE /api/flags/:name.
+func (s *Set) ClearOverrideHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := s.ClearOverride(c.Param("name")); err != nil {
+			s.writeError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (s *Set) writeError(c *gin.Context, err error) {
+	if errors.Is(err, ErrUnknownFlag) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,120 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres"
+)
+
+// ErrDegraded marks a check error as a warning: the check still answers 200, with status "degraded".
+var ErrDegraded = errors.New("degraded")
+
+// Check is a named check that only depends on the configuration, so it can run
+// behind an /api/check endpoint or once from the command line.
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Run     func(ctx context.Context) (Detail, error)
+}
+
+// RunOnce runs the check within its timeout.
+func (c Check) RunOnce(ctx context.Context) (Detail, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	return c.Run(ctx)
+}
+
+var (
+	// The database checks probe every target, each through a short-lived pool; the handler
+	// probes the primary through the application's pool instead (WithDatabase).
+	databaseCheck = Check{Name: "database", Timeout: 2500 * time.Millisecond, Run: func(ctx context.Context) (Detail, error) {
+		return probeTargets(ctx, databaseTargets(configuration.Get().DatabaseConfig, nil), DatabaseByPool)
+	}}
+
+	// databaseDeepCheck is /api/check/database?deep=true.
+	databaseDeepCheck = Check{Name: "database-deep", Timeout: 5 * time.Second, Run: func(ctx context.Context) (Detail, error) {
+		return probeTargets(ctx, databaseTargets(configuration.Get().DatabaseConfig, nil), DatabaseDeep)
+	}}
+
+	migrationsCheck = Check{Name: "migrations", Timeout: 2500 * time.Millisecond, Run: func(ctx context.Context) (Detail, error) {
+		detail, err := withPool(ctx, configuration.Get().DatabaseConfig, Migrations)
+		if errors.Is(err, postgres.ErrNotConfigured) {
+			return Detail{"configured": false}, nil
+		}
+		return detail, err
+	}}
+
+	servicesCheck = Check{Name: "services", Timeout: 2500 * time.Millisecond, Run: func(ctx context.Context) (Detail, error) {
+		var urls []string
+		for _, u := range configuration.Get().ChecksConfig.ServiceURLs {
+			if s := strings.TrimSpace(u); s != "" {
+				urls = append(urls, s)
+			}
+		}
+		if len(urls) == 0 {
+			urls = []string{"https://api.github.com"} // sensible default
+		}
+		return Services(ctx, urls)
+	}}
+
+	metricsCheck = Check{Name: "metrics", Timeout: 800 * time.Millisecond, Run: Metrics}
+
+	configReloadCheck = Check{Name: "config-reload", Timeout: 800 * time.Millisecond, Run: func(ctx context.Context) (Detail, error) {
+		st := configuration.LastReload()
+		detail := Detail{
+			"file":        st.File,
+			"lastAttempt": st.LastAttempt.Format(time.RFC3339),
+			"lastSuccess": st.LastSuccess.Format(time.RFC3339),
+			"reloads":     st.Reloads,
+			"failures":    st.Failures,
+		}
+		if st.Error != "" {
+			return detail, fmt.Errorf("last reload rejected: %s", st.Error)
+		}
+		return detail, nil
+	}}
+
+	fsSelfTestCheck = Check{Name: "fs-selftest", Timeout: 1500 * time.Millisecond, Run: func(ctx context.Context) (Detail, error) {
+		return FilesystemSelfTest(ctx, configuration.Get().AppDataDir)
+	}}
+)
+
+// withPool runs probe on a pool built from cfg (db.dsn, or db.host and its credentials),
+// closed afterwards. It returns postgres.ErrNotConfigured without database settings.
+func withPool(ctx context.Context, cfg *configuration.DatabaseConfig, probe probeFunc) (Detail, error) {
+	pool, err := postgres.NewPool(ctx, cfg)
+	if errors.Is(err, postgres.ErrNotConfigured) {
+		return nil, err
+	}
+	if err != nil {
+		return Detail{"mode": "pool"}, err
+	}
+	defer pool.Close()
+	return probe(ctx, pool)
+}
+
+// Checks returns the registered checks keyed by name.
+func Checks() map[string]Check {
+	checks := map[string]Check{}
+	for _, c := range []Check{databaseCheck, databaseDeepCheck, migrationsCheck, servicesCheck, metricsCheck, configReloadCheck, fsSelfTestCheck} {
+		checks[c.Name] = c
+	}
+	return checks
+}
+
+// CheckNames returns the registered check names, sorted.
+func CheckNames() []string {
+	checks := Checks()
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
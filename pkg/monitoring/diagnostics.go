@@ -0,0 +1,342 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+)
+
+// Severity of a diagnostic measured against its thresholds.
+const (
+	SeverityOK       = "ok"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+	SeverityUnknown  = "unknown" // the query failed, e.g. for lack of privileges
+)
+
+// wraparoundXIDs is how many transaction IDs can be consumed before wraparound.
+const wraparoundXIDs = 1 << 31
+
+// sessionsListed caps the sessions listed by the long query, idle in transaction and lock diagnostics.
+const sessionsListed = 5
+
+// Diagnostic is one measurement of the deep database check.
+type Diagnostic struct {
+	Value    float64 `json:"value"`
+	Unit     string  `json:"unit"`
+	Warning  float64 `json:"warning,omitempty"` // 0: no threshold
+	Critical float64 `json:"critical,omitempty"`
+	Severity string  `json:"severity"`
+	Error    string  `json:"error,omitempty"`
+	Extra    Detail  `json:"extra,omitempty"` // supporting data: sessions, raw counters...
+}
+
+// threshold grades values; lowerIsWorse is for ratios that must stay high.
+type threshold struct {
+	warning, critical float64
+	lowerIsWorse      bool
+}
+
+func (t threshold) grade(v float64) string {
+	reached := func(limit float64) bool {
+		if limit <= 0 {
+			return false
+		}
+		if t.lowerIsWorse {
+			return v < limit
+		}
+		return v >= limit
+	}
+	switch {
+	case reached(t.critical):
+		return SeverityCritical
+	case reached(t.warning):
+		return SeverityWarning
+	}
+	return SeverityOK
+}
+
+// diagnosis is one diagnostic: its query reads the value and the supporting data.
+type diagnosis struct {
+	name      string
+	unit      string
+	threshold threshold
+	measure   func(ctx context.Context, conn *pgxpool.Conn) (float64, Detail, error)
+}
+
+// DatabaseDeep is DatabaseByPool plus the diagnostics the DBAs look at first. A diagnostic
+// reaching its warning threshold, or that cannot be measured, makes the check degraded
+// (ErrDegraded); one reaching its critical threshold fails it.
+func DatabaseDeep(ctx context.Context, pool *pgxpool.Pool) (Detail, error) {
+	detail, err := DatabaseByPool(ctx, pool)
+	if err != nil {
+		return detail, err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return detail, err
+	}
+	defer conn.Release()
+
+	cfg := configuration.Get().DatabaseConfig.Diagnostics
+	diagnostics := map[string]Diagnostic{}
+	var critical, warning []string
+	for _, d := range diagnoses(cfg) {
+		value, extra, err := d.measure(ctx, conn)
+		diag := Diagnostic{
+			Value:    math.Round(value*100) / 100,
+			Unit:     d.unit,
+			Warning:  d.threshold.warning,
+			Critical: d.threshold.critical,
+			Extra:    extra,
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return detail, err
+			}
+			diag.Severity, diag.Error = SeverityUnknown, err.Error()
+			warning = append(warning, fmt.Sprintf("%s: %v", d.name, err))
+		} else {
+			diag.Severity = d.threshold.grade(value)
+		}
+		switch diag.Severity {
+		case SeverityCritical:
+			critical = append(critical, fmt.Sprintf("%s %v%s", d.name, diag.Value, d.unit))
+		case SeverityWarning:
+			warning = append(warning, fmt.Sprintf("%s %v%s", d.name, diag.Value, d.unit))
+		}
+		diagnostics[d.name] = diag
+	}
+	detail["diagnostics"] = diagnostics
+
+	switch {
+	case len(critical) > 0:
+		return detail, fmt.Errorf("critical: %s", strings.Join(critical, ", "))
+	case len(warning) > 0:
+		return detail, fmt.Errorf("%w: %s", ErrDegraded, strings.Join(warning, ", "))
+	}
+	return detail, nil
+}
+
+func diagnoses(cfg configuration.DiagnosticsConfig) []diagnosis {
+	seconds := func(w, c time.Duration) threshold { return threshold{warning: w.Seconds(), critical: c.Seconds()} }
+	return []diagnosis{
+		{
+			name:      "connections",
+			unit:      "%",
+			threshold: threshold{warning: float64(cfg.ConnectionsWarningPct), critical: float64(cfg.ConnectionsCriticalPct)},
+			measure:   measureConnections,
+		},
+		{
+			name:      "replicaLag",
+			unit:      "s",
+			threshold: seconds(cfg.ReplicaLagWarning, cfg.ReplicaLagCritical),
+			measure:   measureReplicaLag,
+		},
+		{
+			name:      "databaseSize",
+			unit:      "MiB",
+			threshold: threshold{warning: float64(cfg.SizeWarningMB), critical: float64(cfg.SizeCriticalMB)},
+			measure:   measureDatabaseSize,
+		},
+		{
+			name:      "longestQuery",
+			unit:      "s",
+			threshold: seconds(cfg.LongQueryWarning, cfg.LongQueryCritical),
+			measure:   sessions(`state = 'active'`, "query_start"),
+		},
+		{
+			name:      "longestIdleInTransaction",
+			unit:      "s",
+			threshold: seconds(cfg.IdleInTxWarning, cfg.IdleInTxCritical),
+			measure:   sessions(`state IN ('idle in transaction', 'idle in transaction (aborted)')`, "state_change"),
+		},
+		{
+			name:      "longestLockWait",
+			unit:      "s",
+			threshold: seconds(cfg.LockWaitWarning, cfg.LockWaitCritical),
+			measure:   measureLockWaits,
+		},
+		{
+			name:      "cacheHitRatio",
+			unit:      "%",
+			threshold: threshold{warning: float64(cfg.CacheHitWarningPct), critical: float64(cfg.CacheHitCriticalPct), lowerIsWorse: true},
+			measure:   measureCacheHit,
+		},
+		{
+			name:      "wraparound",
+			unit:      "%",
+			threshold: threshold{warning: float64(cfg.WraparoundWarningPct), critical: float64(cfg.WraparoundCriticalPct)},
+			measure:   measureWraparound,
+		},
+	}
+}
+
+// measureConnections is the share of max_connections used by client sessions, by state.
+func measureConnections(ctx context.Context, conn *pgxpool.Conn) (float64, Detail, error) {
+	var total, active, idle, idleInTx, maxConns int
+	err := conn.QueryRow(ctx, `
+SELECT count(*),
+       count(*) FILTER (WHERE state = 'active'),
+       count(*) FILTER (WHERE state = 'idle'),
+       count(*) FILTER (WHERE state LIKE 'idle in transaction%'),
+       current_setting('max_connections')::int
+FROM pg_stat_activity
+WHERE backend_type = 'client backend'`).Scan(&total, &active, &idle, &idleInTx, &maxConns)
+	if err != nil {
+		return 0, nil, err
+	}
+	return 100 * float64(total) / float64(maxConns), Detail{
+		"total":             total,
+		"active":            active,
+		"idle":              idle,
+		"idleInTransaction": idleInTx,
+		"maxConnections":    maxConns,
+	}, nil
+}
+
+// measureReplicaLag is how far behind the primary a replica replays; 0 on a primary, or
+// when everything received is replayed (replay timestamps stop moving without writes).
+func measureReplicaLag(ctx context.Context, conn *pgxpool.Conn) (float64, Detail, error) {
+	var (
+		inRecovery bool
+		lag        float64
+	)
+	err := conn.QueryRow(ctx, `
+SELECT pg_is_in_recovery(),
+       CASE
+         WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+         ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+       END::float8`).Scan(&inRecovery, &lag)
+	if err != nil {
+		return 0, nil, err
+	}
+	return lag, Detail{"inRecovery": inRecovery}, nil
+}
+
+func measureDatabaseSize(ctx context.Context, conn *pgxpool.Conn) (float64, Detail, error) {
+	var size int64
+	if err := conn.QueryRow(ctx, `SELECT pg_database_size(current_database())`).Scan(&size); err != nil {
+		return 0, nil, err
+	}
+	return float64(size) / (1 << 20), Detail{"bytes": size}, nil
+}
+
+// sessions measures the oldest client session matching where, aged from the since
+// column of pg_stat_activity, and lists the oldest ones. The checking session is excluded.
+func sessions(where, since string) func(ctx context.Context, conn *pgxpool.Conn) (float64, Detail, error) {
+	query := fmt.Sprintf(`
+SELECT pid, COALESCE(usename, ''), COALESCE(application_name, ''),
+       EXTRACT(EPOCH FROM now() - %[2]s)::float8, left(query, 200)
+FROM pg_stat_activity
+WHERE backend_type = 'client backend' AND pid <> pg_backend_pid() AND %[2]s IS NOT NULL AND %[1]s
+ORDER BY %[2]s
+LIMIT %[3]d`, where, since, sessionsListed)
+	return func(ctx context.Context, conn *pgxpool.Conn) (float64, Detail, error) {
+		rows, err := conn.Query(ctx, query)
+		if err != nil {
+			return 0, nil, err
+		}
+		list, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Detail, error) {
+			var (
+				pid          int32
+				user, app, q string
+				seconds      float64
+			)
+			err := row.Scan(&pid, &user, &app, &seconds, &q)
+			return Detail{"pid": pid, "user": user, "application": app, "seconds": math.Round(seconds), "query": q}, err
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(list) == 0 {
+			return 0, nil, nil
+		}
+		return list[0]["seconds"].(float64), Detail{"sessions": list}, nil
+	}
+}
+
+// measureLockWaits lists the sessions waiting on a lock (wait_event_type Lock) held by others
+// (pg_blocking_pids), the longest waiting first, timed from their last state change.
+func measureLockWaits(ctx context.Context, conn *pgxpool.Conn) (float64, Detail, error) {
+	var blocked int
+	if err := conn.QueryRow(ctx, `
+SELECT count(*) FROM pg_stat_activity
+WHERE wait_event_type = 'Lock' AND cardinality(pg_blocking_pids(pid)) > 0`).Scan(&blocked); err != nil {
+		return 0, nil, err
+	}
+	if blocked == 0 {
+		return 0, Detail{"blocked": 0}, nil
+	}
+	rows, err := conn.Query(ctx, fmt.Sprintf(`
+SELECT pid, pg_blocking_pids(pid), EXTRACT(EPOCH FROM now() - state_change)::float8, left(query, 200)
+FROM pg_stat_activity
+WHERE wait_event_type = 'Lock' AND cardinality(pg_blocking_pids(pid)) > 0 AND state_change IS NOT NULL
+ORDER BY state_change
+LIMIT %d`, sessionsListed))
+	if err != nil {
+		return 0, nil, err
+	}
+	chains, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Detail, error) {
+		var (
+			pid       int32
+			blockedBy []int32
+			seconds   float64
+			q         string
+		)
+		err := row.Scan(&pid, &blockedBy, &seconds, &q)
+		return Detail{"pid": pid, "blockedBy": blockedBy, "seconds": math.Round(seconds), "query": q}, err
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	var longest float64
+	if len(chains) > 0 {
+		longest = chains[0]["seconds"].(float64)
+	}
+	return longest, Detail{"blocked": blocked, "sessions": chains}, nil
+}
+
+// measureCacheHit is the share of block reads of the current database served by shared buffers.
+func measureCacheHit(ctx context.Context, conn *pgxpool.Conn) (float64, Detail, error) {
+	var hit, read int64
+	if err := conn.QueryRow(ctx, `
+SELECT blks_hit, blks_read FROM pg_stat_database WHERE datname = current_database()`).Scan(&hit, &read); err != nil {
+		return 0, nil, err
+	}
+	ratio := 100.0
+	if hit+read > 0 {
+		ratio = 100 * float64(hit) / float64(hit+read)
+	}
+	return ratio, Detail{"blocksHit": hit, "blocksRead": read}, nil
+}
+
+// measureWraparound is the share of transaction IDs consumed by the oldest unfrozen
+// database of the cluster: autovacuum must freeze it before 100%.
+func measureWraparound(ctx context.Context, conn *pgxpool.Conn) (float64, Detail, error) {
+	var (
+		datname string
+		age     int64
+	)
+	err := conn.QueryRow(ctx, `
+SELECT datname, age(datfrozenxid) FROM pg_database ORDER BY age(datfrozenxid) DESC LIMIT 1`).Scan(&datname, &age)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return 100 * float64(age) / wraparoundXIDs, Detail{
+		"database": datname,
+		"xidAge":   age,
+		"headroom": wraparoundXIDs - age,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package monitoring
+
+import "testing"
+
+func TestThresholdGrade(t *testing.T) {
+	higher := threshold{warning: 80, critical: 90}
+	lower := threshold{warning: 99, critical: 95, lowerIsWorse: true}
+	tests := []struct {
+		name string
+		t    threshold
+		v    float64
+		want string
+	}{
+		{"below the warning", higher, 79.9, SeverityOK},
+		{"at the warning", higher, 80, SeverityWarning},
+		{"between", higher, 89.9, SeverityWarning},
+		{"at the critical", higher, 90, SeverityCritical},
+		{"above the critical", higher, 1000, SeverityCritical},
+		{"zero", higher, 0, SeverityOK},
+
+		{"lower is worse, above the warning", lower, 99.5, SeverityOK},
+		{"lower is worse, at the warning", lower, 99, SeverityOK},
+		{"lower is worse, below the warning", lower, 98.9, SeverityWarning},
+		{"lower is worse, at the critical", lower, 95, SeverityWarning},
+		{"lower is worse, below the critical", lower, 94.9, SeverityCritical},
+
+		{"no threshold", threshold{}, 1e9, SeverityOK},
+		{"warning only", threshold{warning: 10}, 1e9, SeverityWarning},
+		{"critical only", threshold{critical: 10}, 10, SeverityCritical},
+		{"critical only, below", threshold{critical: 10}, 9, SeverityOK},
+		{"lower is worse without threshold", threshold{lowerIsWorse: true}, 0, SeverityOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.t.grade(tt.v); got != tt.want {
+				t.Errorf("%+v.grade(%v) = %s, want %s", tt.t, tt.v, got, tt.want)
+			}
+		})
+	}
+}
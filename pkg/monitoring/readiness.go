@@ -0,0 +1,128 @@
+package monitoring
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultGateTimeout bounds a gate check that does not set its own timeout.
+const defaultGateTimeout = 2 * time.Second
+
+// Gate is one condition the service needs to receive traffic (data dir writable,
+// database reachable, warm-up finished...). Check follows the checker convention:
+// a non-nil error fails the gate, Detail is reported with ?verbose.
+type Gate struct {
+	Name    string
+	Check   func(ctx context.Context) (Detail, error)
+	Timeout time.Duration // defaultGateTimeout when zero
+	// CacheFor reuses the last result for this long so frequent probes stay cheap; zero checks every time.
+	CacheFor time.Duration
+}
+
+// GateResult is the outcome of one gate. Only Name and Status are reported without ?verbose.
+type GateResult struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"` // ok | failed | excluded
+	Error     string    `json:"error,omitempty"`
+	Detail    Detail    `json:"detail,omitempty"`
+	CheckedAt time.Time `json:"checkedAt,omitzero"`
+	LatencyMs int64     `json:"latencyMs,omitempty"`
+	Cached    bool      `json:"cached,omitempty"`
+}
+
+// Readiness is the set of gates behind /api/readyz.
+type Readiness struct {
+	mu    sync.RWMutex
+	gates []*gate
+}
+
+type gate struct {
+	Gate
+	mu   sync.Mutex // serializes checks so concurrent probes share one result
+	last GateResult
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+// Add registers a gate, replacing any gate with the same name.
+func (r *Readiness) Add(g Gate) {
+	if g.Timeout <= 0 {
+		g.Timeout = defaultGateTimeout
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.gates {
+		if existing.Name == g.Name {
+			r.gates[i] = &gate{Gate: g}
+			return
+		}
+	}
+	r.gates = append(r.gates, &gate{Gate: g})
+}
+
+// Evaluate checks every gate not in exclude, concurrently, and reports whether all passed.
+// Results keep registration order.
+func (r *Readiness) Evaluate(ctx context.Context, exclude map[string]bool) ([]GateResult, bool) {
+	r.mu.RLock()
+	gates := append([]*gate(nil), r.gates...)
+	r.mu.RUnlock()
+
+	results := make([]GateResult, len(gates))
+	var wg sync.WaitGroup
+	for i, g := range gates {
+		if exclude[g.Name] {
+			results[i] = GateResult{Name: g.Name, Status: "excluded"}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = g.evaluate(ctx)
+		}()
+	}
+	wg.Wait()
+
+	ready := true
+	for _, res := range results {
+		if res.Status == "failed" {
+			ready = false
+		}
+	}
+	return results, ready
+}
+
+func (g *gate) evaluate(ctx context.Context) GateResult {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.CacheFor > 0 && !g.last.CheckedAt.IsZero() && time.Since(g.last.CheckedAt) < g.CacheFor {
+		res := g.last
+		res.Cached = true
+		return res
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, g.Timeout)
+	defer cancel()
+	start := time.Now()
+	detail, err := g.Check(checkCtx)
+
+	res := GateResult{
+		Name:      g.Name,
+		Status:    "ok",
+		Detail:    detail,
+		CheckedAt: start.UTC(),
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		res.Status = "failed"
+		res.Error = err.Error()
+	}
+	// A probe given up by its caller says nothing about the gate: do not serve it to the next ones.
+	if ctx.Err() == nil {
+		g.last = res
+	}
+	return res
+}
@@ -0,0 +1,203 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func passing(context.Context) (Detail, error) { return Detail{"rows": 1}, nil }
+
+func failing(context.Context) (Detail, error) { return nil, errors.New("unreachable") }
+
+func TestReadinessEvaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		gates     []Gate
+		exclude   map[string]bool
+		want      []string // name:status, in registration order
+		wantReady bool
+	}{
+		{name: "no gate", want: []string{}, wantReady: true},
+		{
+			name:      "every gate passes",
+			gates:     []Gate{{Name: "a", Check: passing}, {Name: "b", Check: passing}},
+			want:      []string{"a:ok", "b:ok"},
+			wantReady: true,
+		},
+		{
+			name:  "one failed gate fails readiness",
+			gates: []Gate{{Name: "a", Check: passing}, {Name: "b", Check: failing}, {Name: "c", Check: passing}},
+			want:  []string{"a:ok", "b:failed", "c:ok"},
+		},
+		{
+			name:      "the failed gate is excluded",
+			gates:     []Gate{{Name: "a", Check: passing}, {Name: "b", Check: failing}},
+			exclude:   map[string]bool{"b": true},
+			want:      []string{"a:ok", "b:excluded"},
+			wantReady: true,
+		},
+		{
+			name:    "excluding another gate",
+			gates:   []Gate{{Name: "a", Check: passing}, {Name: "b", Check: failing}},
+			exclude: map[string]bool{"a": true, "unknown": true},
+			want:    []string{"a:excluded", "b:failed"},
+		},
+		{
+			name:      "a gate replaces the one of the same name",
+			gates:     []Gate{{Name: "a", Check: failing}, {Name: "b", Check: passing}, {Name: "a", Check: passing}},
+			want:      []string{"a:ok", "b:ok"},
+			wantReady: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReadiness()
+			for _, g := range tt.gates {
+				r.Add(g)
+			}
+			results, ready := r.Evaluate(context.Background(), tt.exclude)
+			got := []string{}
+			for _, res := range results {
+				got = append(got, res.Name+":"+res.Status)
+			}
+			if !slices.Equal(got, tt.want) || ready != tt.wantReady {
+				t.Errorf("Evaluate() = %v, %v; want %v, %v", got, ready, tt.want, tt.wantReady)
+			}
+		})
+	}
+}
+
+// countingGate is a gate counting its checks, failing while fail is set.
+func countingGate(cacheFor time.Duration, calls *atomic.Int32, fail *atomic.Bool) Gate {
+	return Gate{Name: "counted", CacheFor: cacheFor, Check: func(ctx context.Context) (Detail, error) {
+		calls.Add(1)
+		if fail.Load() {
+			return nil, errors.New("down")
+		}
+		return nil, ctx.Err()
+	}}
+}
+
+func TestReadinessCache(t *testing.T) {
+	tests := []struct {
+		name       string
+		cacheFor   time.Duration
+		wait       time.Duration // between the two evaluations
+		wantCalls  int32
+		wantCached bool
+	}{
+		{name: "no cache", cacheFor: 0, wantCalls: 2},
+		{name: "cache hit", cacheFor: time.Hour, wantCalls: 1, wantCached: true},
+		{name: "cache expired", cacheFor: time.Millisecond, wait: 5 * time.Millisecond, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			var fail atomic.Bool
+			r := NewReadiness()
+			r.Add(countingGate(tt.cacheFor, &calls, &fail))
+
+			first, _ := r.Evaluate(context.Background(), nil)
+			time.Sleep(tt.wait)
+			second, _ := r.Evaluate(context.Background(), nil)
+			if calls.Load() != tt.wantCalls || second[0].Cached != tt.wantCached {
+				t.Errorf("%d checks, cached %v; want %d, %v", calls.Load(), second[0].Cached, tt.wantCalls, tt.wantCached)
+			}
+			if tt.wantCached && !second[0].CheckedAt.Equal(first[0].CheckedAt) {
+				t.Errorf("cached result checked at %v, want %v", second[0].CheckedAt, first[0].CheckedAt)
+			}
+		})
+	}
+}
+
+func TestReadinessCacheKeepsFailures(t *testing.T) {
+	var calls atomic.Int32
+	var fail atomic.Bool
+	r := NewReadiness()
+	r.Add(countingGate(time.Hour, &calls, &fail))
+
+	fail.Store(true)
+	if _, ready := r.Evaluate(context.Background(), nil); ready {
+		t.Fatal("ready with a failed gate")
+	}
+	fail.Store(false)
+	if _, ready := r.Evaluate(context.Background(), nil); ready || calls.Load() != 1 {
+		t.Errorf("ready %v after %d checks, want the failure served from the cache", ready, calls.Load())
+	}
+}
+
+func TestReadinessCanceledProbeIsNotCached(t *testing.T) {
+	var calls atomic.Int32
+	var fail atomic.Bool
+	r := NewReadiness()
+	r.Add(countingGate(time.Hour, &calls, &fail))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ready := r.Evaluate(ctx, nil); ready {
+		t.Fatal("ready although the probe was canceled")
+	}
+	results, ready := r.Evaluate(context.Background(), nil)
+	if !ready || results[0].Cached || calls.Load() != 2 {
+		t.Errorf("ready %v, cached %v after %d checks; want the gate checked again", ready, results[0].Cached, calls.Load())
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		query       string
+		wantCode    int
+		wantDB      string // status of the db gate
+		wantVerbose bool   // gates carry their error, detail and timings
+	}{
+		{query: "", wantCode: http.StatusServiceUnavailable, wantDB: "failed"},
+		{query: "?verbose", wantCode: http.StatusServiceUnavailable, wantDB: "failed", wantVerbose: true},
+		{query: "?exclude=db", wantCode: http.StatusOK, wantDB: "excluded"},
+		{query: "?exclude=other&exclude=db", wantCode: http.StatusOK, wantDB: "excluded"},
+		{query: "?exclude=other,%20db&verbose", wantCode: http.StatusOK, wantDB: "excluded", wantVerbose: true},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := NewReadiness()
+			r.Add(Gate{Name: "data", Check: passing})
+			r.Add(Gate{Name: "db", Check: failing})
+			engine := gin.New()
+			engine.GET("/readyz", New(WithReadiness(r)).Readyz())
+
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz"+tt.query, nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d", rec.Code, tt.wantCode)
+			}
+			var body struct {
+				Gates []GateResult `json:"gates"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Gates) != 2 {
+				t.Fatalf("gates = %+v, want data and db", body.Gates)
+			}
+			data, db := body.Gates[0], body.Gates[1]
+			if db.Status != tt.wantDB {
+				t.Errorf("db gate %s, want %s", db.Status, tt.wantDB)
+			}
+			if verbose := data.Detail != nil && !data.CheckedAt.IsZero(); verbose != tt.wantVerbose {
+				t.Errorf("data gate = %+v, want verbose: %v", data, tt.wantVerbose)
+			}
+			if tt.wantDB == "failed" && (db.Error != "") != tt.wantVerbose {
+				t.Errorf("db gate error %q, want verbose: %v", db.Error, tt.wantVerbose)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+	"github.com/khedhrije/tools-archetype/internal/infrastructure/postgres"
+)
+
+// ErrUnknownTarget is returned for a database target that is not configured.
+var ErrUnknownTarget = errors.New("unknown database target")
+
+// probeFunc checks one database through a pool, e.g. DatabaseByPool or DatabaseDeep.
+type probeFunc func(context.Context, *pgxpool.Pool) (Detail, error)
+
+// dbTarget is one database probed by the database check: the application's (PrimaryTarget)
+// and the ones of db.targets.
+type dbTarget struct {
+	name     string
+	role     string // expected role, empty when not verified
+	critical bool
+	cfg      *configuration.DatabaseConfig
+	pool     *pgxpool.Pool // nil: the probe opens its own
+}
+
+// databaseTargets lists the application's database, probed through pool when it is not nil, then
+// db.targets by name.
+func databaseTargets(cfg *configuration.DatabaseConfig, pool *pgxpool.Pool) []dbTarget {
+	targets := []dbTarget{{name: configuration.PrimaryTarget, role: cfg.Role, critical: true, cfg: cfg, pool: pool}}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Targets)) {
+		t := cfg.Targets[name]
+		tc := *cfg // keeps db.tls, db.statement_timeout and the pool sizes
+		tc.DSN = t.DSN
+		tc.Targets = nil
+		targets = append(targets, dbTarget{name: name, role: t.Role, critical: t.IsCritical(), cfg: &tc})
+	}
+	return targets
+}
+
+// databaseTarget returns the target called name.
+func databaseTarget(cfg *configuration.DatabaseConfig, pool *pgxpool.Pool, name string) (dbTarget, error) {
+	for _, t := range databaseTargets(cfg, pool) {
+		if t.name == name {
+			return t, nil
+		}
+	}
+	return dbTarget{}, fmt.Errorf("%w: %q", ErrUnknownTarget, name)
+}
+
+// probe runs fn on the target, then checks the role the server reports against the expected
+// one: a primary that turns out to be a replica (or the reverse, after a failover) is degraded.
+func (t dbTarget) probe(ctx context.Context, fn probeFunc) (Detail, error) {
+	if t.pool != nil {
+		return t.checkRole(fn(ctx, t.pool))
+	}
+	detail, err := withPool(ctx, t.cfg, fn)
+	if errors.Is(err, postgres.ErrNotConfigured) {
+		// Fallback: plain TCP reachability if only APP_DB_ADDR is set
+		return DatabaseByTCP(ctx, t.cfg.Addr)
+	}
+	return t.checkRole(detail, err)
+}
+
+func (t dbTarget) checkRole(detail Detail, err error) (Detail, error) {
+	if err != nil {
+		return detail, err
+	}
+	role, _ := detail["role"].(string)
+	if t.role != "" && role != "" && role != t.role {
+		return detail, fmt.Errorf("%w: configured as %s but the server is a %s", ErrDegraded, t.role, role)
+	}
+	return detail, nil
+}
+
+// probeTargets probes the targets concurrently and reports each under "targets", with its
+// status, latency and expected role. A failing critical target fails the check; a failing
+// non-critical one, or a degraded one, makes it degraded.
+func probeTargets(ctx context.Context, targets []dbTarget, fn probeFunc) (Detail, error) {
+	results := make([]Detail, len(targets))
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			detail, err := t.probe(ctx, fn)
+			results[i] = targetResult(t, detail, err, time.Since(start))
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	out := make(Detail, len(targets))
+	var failed, degraded []string
+	for i, t := range targets {
+		out[t.name] = results[i]
+		switch err := errs[i]; {
+		case err == nil:
+		case t.critical && !errors.Is(err, ErrDegraded):
+			failed = append(failed, fmt.Sprintf("%s: %v", t.name, err))
+		default:
+			degraded = append(degraded, fmt.Sprintf("%s: %v", t.name, strings.TrimPrefix(err.Error(), ErrDegraded.Error()+": ")))
+		}
+	}
+	detail := Detail{"targets": out}
+	if len(failed) > 0 {
+		return detail, errors.New(strings.Join(append(failed, degraded...), "; "))
+	}
+	if len(degraded) > 0 {
+		return detail, fmt.Errorf("%w: %s", ErrDegraded, strings.Join(degraded, "; "))
+	}
+	return detail, nil
+}
+
+// targetResult is the entry of one target, shaped like a check response, with the role
+// its server reports when it could be read.
+func targetResult(t dbTarget, detail Detail, err error, lat time.Duration) Detail {
+	r := Detail{
+		"status":       "ok",
+		"critical":     t.critical,
+		"expectedRole": t.role,
+		"latencyMs":    lat.Milliseconds(),
+		"detail":       detail,
+	}
+	if role, ok := detail["role"].(string); ok {
+		r["role"] = role
+	}
+	switch {
+	case errors.Is(err, ErrDegraded):
+		r["status"] = "degraded"
+		r["warning"] = err.Error()
+	case err != nil:
+		r["status"] = "error"
+		r["error"] = err.Error()
+	}
+	return r
+}
@@ -0,0 +1,196 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/khedhrije/tools-archetype/internal/configuration"
+)
+
+func TestDatabaseTargets(t *testing.T) {
+	notCritical := false
+	pool := &pgxpool.Pool{}
+	cfg := &configuration.DatabaseConfig{
+		Role: "replica",
+		Targets: map[string]configuration.DatabaseTargetConfig{
+			"replica-2": {DSN: "host=r2", Role: "replica"},
+			"analytics": {DSN: "host=a", Critical: &notCritical},
+		},
+	}
+	targets := databaseTargets(cfg, pool)
+	want := []struct {
+		name, role string
+		critical   bool
+		pooled     bool
+	}{
+		{configuration.PrimaryTarget, "replica", true, true},
+		{"analytics", "", false, false},
+		{"replica-2", "replica", true, false},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("databaseTargets() = %+v", targets)
+	}
+	for i, w := range want {
+		got := targets[i]
+		if got.name != w.name || got.role != w.role || got.critical != w.critical || (got.pool != nil) != w.pooled {
+			t.Errorf("target %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if targets[2].cfg.DSN != "host=r2" || targets[2].cfg.Targets != nil {
+		t.Errorf("replica-2 config = %+v", targets[2].cfg)
+	}
+	if _, err := databaseTarget(cfg, pool, "missing"); !errors.Is(err, ErrUnknownTarget) {
+		t.Errorf("databaseTarget(missing) error = %v", err)
+	}
+}
+
+func TestCheckRole(t *testing.T) {
+	tests := []struct {
+		expected, reported string
+		wantDegraded       bool
+	}{
+		{"", "primary", false},
+		{"", "replica", false},
+		{"primary", "primary", false},
+		{"primary", "replica", true},
+		{"replica", "primary", true},
+		{"replica", "", false}, // role not reported, e.g. TCP fallback
+	}
+	for _, tt := range tests {
+		detail := Detail{}
+		if tt.reported != "" {
+			detail["role"] = tt.reported
+		}
+		_, err := dbTarget{role: tt.expected}.checkRole(detail, nil)
+		if errors.Is(err, ErrDegraded) != tt.wantDegraded {
+			t.Errorf("expected %q, reported %q: error = %v, want degraded: %v", tt.expected, tt.reported, err, tt.wantDegraded)
+		}
+	}
+	boom := errors.New("boom")
+	if _, err := (dbTarget{role: "primary"}).checkRole(nil, boom); err != boom {
+		t.Errorf("checkRole() = %v, want the probe error", err)
+	}
+}
+
+// fakeProbe answers, for the target probed through each pool, the role it reports or an error.
+type fakeProbe map[*pgxpool.Pool]struct {
+	role string
+	err  error
+}
+
+func (f fakeProbe) probe(_ context.Context, pool *pgxpool.Pool) (Detail, error) {
+	r := f[pool]
+	if r.err != nil {
+		return nil, r.err
+	}
+	return Detail{"role": r.role}, nil
+}
+
+func TestProbeTargets(t *testing.T) {
+	type target struct {
+		name, expected string
+		critical       bool
+		reported       string // role
+		err            error
+	}
+	tests := []struct {
+		name         string
+		targets      []target
+		wantErr      string // "" when the check passes
+		wantDegraded bool
+		wantStatus   map[string]string
+	}{
+		{
+			name: "all ok",
+			targets: []target{
+				{name: "primary", expected: "primary", critical: true, reported: "primary"},
+				{name: "replica-1", expected: "replica", critical: true, reported: "replica"},
+			},
+			wantStatus: map[string]string{"primary": "ok", "replica-1": "ok"},
+		},
+		{
+			name: "role not verified",
+			targets: []target{
+				{name: "primary", critical: true, reported: "replica"},
+			},
+			wantStatus: map[string]string{"primary": "ok"},
+		},
+		{
+			name: "a non-critical target fails",
+			targets: []target{
+				{name: "primary", critical: true, reported: "primary"},
+				{name: "analytics", err: errors.New("connection refused")},
+			},
+			wantErr:      "analytics: connection refused",
+			wantDegraded: true,
+			wantStatus:   map[string]string{"primary": "ok", "analytics": "error"},
+		},
+		{
+			name: "a critical target fails",
+			targets: []target{
+				{name: "primary", critical: true, reported: "primary"},
+				{name: "replica-1", critical: true, err: errors.New("timeout")},
+			},
+			wantErr:    "replica-1: timeout",
+			wantStatus: map[string]string{"primary": "ok", "replica-1": "error"},
+		},
+		{
+			name: "a critical target has the wrong role",
+			targets: []target{
+				{name: "primary", expected: "primary", critical: true, reported: "replica"},
+			},
+			wantErr:      "primary: configured as primary but the server is a replica",
+			wantDegraded: true,
+			wantStatus:   map[string]string{"primary": "degraded"},
+		},
+		{
+			name: "critical and non-critical failures",
+			targets: []target{
+				{name: "primary", critical: true, err: errors.New("down")},
+				{name: "analytics", err: errors.New("refused")},
+			},
+			wantErr:    "primary: down; analytics: refused",
+			wantStatus: map[string]string{"primary": "error", "analytics": "error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := fakeProbe{}
+			var targets []dbTarget
+			for _, tg := range tt.targets {
+				pool := &pgxpool.Pool{}
+				fake[pool] = struct {
+					role string
+					err  error
+				}{tg.reported, tg.err}
+				targets = append(targets, dbTarget{name: tg.name, role: tg.expected, critical: tg.critical, pool: pool})
+			}
+
+			detail, err := probeTargets(context.Background(), targets, fake.probe)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("probeTargets() error = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) || errors.Is(err, ErrDegraded) != tt.wantDegraded {
+				t.Errorf("probeTargets() error = %v, want %q, degraded: %v", err, tt.wantErr, tt.wantDegraded)
+			}
+
+			results := detail["targets"].(Detail)
+			for name, want := range tt.wantStatus {
+				r := results[name].(Detail)
+				if r["status"] != want {
+					t.Errorf("target %s = %+v, want status %s", name, r, want)
+				}
+			}
+			for _, tg := range tt.targets {
+				r := results[tg.name].(Detail)
+				if role, _ := r["role"].(string); role != tg.reported {
+					t.Errorf("target %s role %q, want the reported %q", tg.name, role, tg.reported)
+				}
+			}
+		})
+	}
+}
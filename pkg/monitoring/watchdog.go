@@ -0,0 +1,151 @@
+package monitoring
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// heartbeatTolerance is how many intervals a heartbeat may miss before it is overdue,
+// so a loop that is merely slow once does not get the pod restarted.
+const heartbeatTolerance = 3
+
+// Runtime memory as seen by the OS: everything mapped by the Go runtime minus what was returned.
+const (
+	memTotalMetric    = "/memory/classes/total:bytes"
+	memReleasedMetric = "/memory/classes/heap/released:bytes"
+)
+
+// WatchdogLimits are the process ceilings checked by the watchdog; zero disables one.
+type WatchdogLimits struct {
+	MaxGoroutines  int
+	MaxMemoryBytes uint64
+}
+
+// Watchdog backs /api/livez: it fails when a registered heartbeat is overdue or the
+// process exceeds its goroutine or memory ceiling, i.e. when a restart is the fix.
+type Watchdog struct {
+	limits func() WatchdogLimits
+
+	mu    sync.Mutex
+	beats map[string]*Heartbeat
+}
+
+// NewWatchdog returns a watchdog reading its ceilings from limits on every check,
+// so they can follow configuration reloads. limits may be nil.
+func NewWatchdog(limits func() WatchdogLimits) *Watchdog {
+	if limits == nil {
+		limits = func() WatchdogLimits { return WatchdogLimits{} }
+	}
+	return &Watchdog{limits: limits, beats: map[string]*Heartbeat{}}
+}
+
+// Heartbeat is the proof of life of one long-running loop.
+type Heartbeat struct {
+	w        *Watchdog
+	name     string
+	interval time.Duration
+	last     atomic.Int64 // unix nanoseconds
+}
+
+// Register adds a heartbeat the loop must Beat at least every interval.
+// Registering an existing name replaces it.
+func (w *Watchdog) Register(name string, interval time.Duration) *Heartbeat {
+	hb := &Heartbeat{w: w, name: name, interval: interval}
+	hb.Beat()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.beats[name] = hb
+	return hb
+}
+
+// Beat records that the loop is alive. Call it once per iteration.
+func (hb *Heartbeat) Beat() {
+	hb.last.Store(time.Now().UnixNano())
+}
+
+// Stop unregisters the heartbeat; call it when the loop exits on purpose.
+func (hb *Heartbeat) Stop() {
+	hb.w.mu.Lock()
+	defer hb.w.mu.Unlock()
+	if hb.w.beats[hb.name] == hb {
+		delete(hb.w.beats, hb.name)
+	}
+}
+
+// HeartbeatStatus is one heartbeat as reported by Check.
+type HeartbeatStatus struct {
+	Name     string    `json:"name"`
+	Interval string    `json:"interval"`
+	LastSeen time.Time `json:"lastSeen"`
+	Overdue  bool      `json:"overdue"`
+}
+
+// Check reports the heartbeats and the runtime against the ceilings. The error names
+// the overdue heartbeats and the exceeded ceilings.
+func (w *Watchdog) Check() (Detail, error) {
+	w.mu.Lock()
+	beats := make([]*Heartbeat, 0, len(w.beats))
+	for _, hb := range w.beats {
+		beats = append(beats, hb)
+	}
+	w.mu.Unlock()
+	sort.Slice(beats, func(i, j int) bool { return beats[i].name < beats[j].name })
+
+	var problems []string
+	now := time.Now()
+	statuses := make([]HeartbeatStatus, 0, len(beats))
+	for _, hb := range beats {
+		last := time.Unix(0, hb.last.Load())
+		overdue := now.Sub(last) > heartbeatTolerance*hb.interval
+		statuses = append(statuses, HeartbeatStatus{
+			Name:     hb.name,
+			Interval: hb.interval.String(),
+			LastSeen: last.UTC(),
+			Overdue:  overdue,
+		})
+		if overdue {
+			problems = append(problems, fmt.Sprintf("heartbeat %s overdue (last seen %s ago)", hb.name, now.Sub(last).Round(time.Second)))
+		}
+	}
+
+	limits := w.limits()
+	goroutines := runtime.NumGoroutine()
+	memory := runtimeMemory()
+	if limits.MaxGoroutines > 0 && goroutines > limits.MaxGoroutines {
+		problems = append(problems, fmt.Sprintf("%d goroutines exceed the ceiling of %d", goroutines, limits.MaxGoroutines))
+	}
+	if limits.MaxMemoryBytes > 0 && memory > limits.MaxMemoryBytes {
+		problems = append(problems, fmt.Sprintf("%d bytes of memory exceed the ceiling of %d", memory, limits.MaxMemoryBytes))
+	}
+
+	detail := Detail{
+		"heartbeats":     statuses,
+		"goroutines":     goroutines,
+		"maxGoroutines":  limits.MaxGoroutines,
+		"memoryBytes":    memory,
+		"maxMemoryBytes": limits.MaxMemoryBytes,
+	}
+	if len(problems) > 0 {
+		return detail, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return detail, nil
+}
+
+func runtimeMemory() uint64 {
+	samples := []metrics.Sample{{Name: memTotalMetric}, {Name: memReleasedMetric}}
+	metrics.Read(samples)
+	var total, released uint64
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		total = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		released = samples[1].Value.Uint64()
+	}
+	return total - released
+}
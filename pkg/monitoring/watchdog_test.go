@@ -0,0 +1,134 @@
+package monitoring
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWatchdogHeartbeats(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		lastSeen time.Duration // ago
+		wantErr  bool
+	}{
+		{name: "fresh", interval: time.Second, lastSeen: 0},
+		{name: "one interval missed", interval: time.Second, lastSeen: 1500 * time.Millisecond},
+		{name: "within the tolerance", interval: time.Second, lastSeen: 2900 * time.Millisecond},
+		{name: "beyond the tolerance", interval: time.Second, lastSeen: 3100 * time.Millisecond, wantErr: true},
+		{name: "long stale", interval: 10 * time.Millisecond, lastSeen: time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWatchdog(nil)
+			hb := w.Register("loop", tt.interval)
+			hb.last.Store(time.Now().Add(-tt.lastSeen).UnixNano())
+
+			detail, err := w.Check()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, want an error: %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "heartbeat loop overdue") {
+				t.Errorf("Check() error = %v, want it to name the heartbeat", err)
+			}
+			statuses := detail["heartbeats"].([]HeartbeatStatus)
+			if len(statuses) != 1 || statuses[0].Overdue != tt.wantErr {
+				t.Errorf("heartbeats = %+v", statuses)
+			}
+		})
+	}
+}
+
+func TestWatchdogStaleHeartbeat(t *testing.T) {
+	w := NewWatchdog(nil)
+	hb := w.Register("loop", 5*time.Millisecond)
+	if _, err := w.Check(); err != nil {
+		t.Fatalf("a fresh heartbeat fails: %v", err)
+	}
+	time.Sleep(heartbeatTolerance*5*time.Millisecond + 20*time.Millisecond)
+	if _, err := w.Check(); err == nil {
+		t.Fatal("a stale heartbeat passes")
+	}
+	hb.Beat()
+	if _, err := w.Check(); err != nil {
+		t.Errorf("the heartbeat beat again and still fails: %v", err)
+	}
+
+	time.Sleep(heartbeatTolerance*5*time.Millisecond + 20*time.Millisecond)
+	hb.Stop()
+	if _, err := w.Check(); err != nil {
+		t.Errorf("a stopped heartbeat fails: %v", err)
+	}
+}
+
+func TestWatchdogCeilings(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  WatchdogLimits
+		wantErr string
+	}{
+		{name: "no ceiling", limits: WatchdogLimits{}},
+		{name: "below the ceilings", limits: WatchdogLimits{MaxGoroutines: 1 << 20, MaxMemoryBytes: 1 << 40}},
+		{name: "goroutines exceeded", limits: WatchdogLimits{MaxGoroutines: 1}, wantErr: "goroutines exceed the ceiling of 1"},
+		{name: "memory exceeded", limits: WatchdogLimits{MaxMemoryBytes: 1}, wantErr: "bytes of memory exceed the ceiling of 1"},
+		{
+			name:    "both exceeded",
+			limits:  WatchdogLimits{MaxGoroutines: 1, MaxMemoryBytes: 1},
+			wantErr: "goroutines exceed the ceiling of 1; ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWatchdog(func() WatchdogLimits { return tt.limits })
+			detail, err := w.Check()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if detail["goroutines"].(int) < 1 || detail["memoryBytes"].(uint64) == 0 {
+				t.Errorf("detail = %+v", detail)
+			}
+		})
+	}
+}
+
+func TestWatchdogFollowsLimits(t *testing.T) {
+	limits := WatchdogLimits{MaxGoroutines: 1}
+	w := NewWatchdog(func() WatchdogLimits { return limits })
+	if _, err := w.Check(); err == nil {
+		t.Fatal("the goroutine ceiling is not enforced")
+	}
+	limits.MaxGoroutines = 0
+	if _, err := w.Check(); err != nil {
+		t.Errorf("the ceiling was lifted and Check() still fails: %v", err)
+	}
+}
+
+func TestLivez(t *testing.T) {
+	w := NewWatchdog(nil)
+	hb := w.Register("loop", time.Second)
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/livez", New(WithWatchdog(w)).Livez())
+
+	probe := func() int {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+		return rec.Code
+	}
+	if code := probe(); code != http.StatusOK {
+		t.Errorf("fresh heartbeat: status %d, want 200", code)
+	}
+	hb.last.Store(time.Now().Add(-time.Hour).UnixNano())
+	if code := probe(); code != http.StatusServiceUnavailable {
+		t.Errorf("stale heartbeat: status %d, want 503", code)
+	}
+}
@@ -0,0 +1,17 @@
+package requestid
+
+import "github.com/gin-gonic/gin"
+
+// Middleware keeps a valid X-Request-ID from the caller or generates one, stores it in the
+// request context and echoes it in the response.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if !valid(id) {
+			id = New()
+		}
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), id))
+		c.Header(Header, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+// Package requestid tags every request with an ID, taken from the caller's X-Request-ID
+// header or generated, so logs of the same request (slow queries, ...) can be correlated.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header carries the request ID in both directions.
+const Header = "X-Request-ID"
+
+// maxLen bounds the IDs accepted from callers, which end up in the logs.
+const maxLen = 128
+
+type ctxKey struct{}
+
+// New returns a random 128-bit ID, hex encoded.
+func New() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID stored by Middleware, empty outside a request.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// valid accepts printable ASCII IDs of reasonable length, so callers cannot forge log lines.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}